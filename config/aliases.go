@@ -55,16 +55,12 @@ func (a *Aliases) Load(path string) (err error) {
 	return
 }
 
-func (a *Aliases) Write(path string) (err error) {
-	m := make(map[string][]string)
+// Write the aliases, keeping the comments of the file, and the order of its artists if keepOrder is set.
+func (a *Aliases) Write(path string, keepOrder bool) (err error) {
+	lists := make(map[string][]string)
 	for _, alias := range *a {
-		m[alias.MainAlias] = alias.Aliases
+		lists[alias.MainAlias] = alias.Aliases
 	}
-
-	d, err := yaml.Marshal(&m)
-	if err != nil {
-		return
-	}
-	err = ioutil.WriteFile(path, d, 0777)
-	return
+	lf := listFile{lists: lists, less: func(a, b string) bool { return strings.ToLower(a) < strings.ToLower(b) }}
+	return lf.write(path, keepOrder)
 }
@@ -9,13 +9,19 @@ import (
 
 // Config holds the configuration for radis.
 type Config struct {
-	Paths   Paths
-	Aliases Aliases
-	Genres  Genres
+	Paths      Paths
+	Aliases    Aliases
+	Genres     Genres
+	Mirror     Mirror
+	MPD        MPD
+	FilePolicy FilePolicy
+	Rolling    RollingPlaylists
+	Profiles   ExportProfiles
+	Playlists  SmartPlaylists
 }
 
 func (c *Config) String() string {
-	return c.Paths.String() + c.Aliases.String() + c.Genres.String()
+	return c.Paths.String() + c.Mirror.String() + c.MPD.String() + c.FilePolicy.String() + c.Rolling.String() + c.Profiles.String() + c.Aliases.String() + c.Genres.String() + c.Playlists.String()
 }
 
 // Check the configuration for errors.
@@ -25,14 +31,23 @@ func (c *Config) Check() error {
 }
 
 const (
-	radis                  = "radis"
-	radisGenresConfigFile  = radis + "_genres.yaml"
-	radisAliasesConfigFile = radis + "_aliases.yaml"
-	xdgMainPath            = radis + "/" + radis + ".yaml"
-	xdgGenrePath           = radis + "/" + radisGenresConfigFile
-	xdgAliasPath           = radis + "/" + radisAliasesConfigFile
+	radis                    = "radis"
+	radisMainConfigFile      = radis + ".yaml"
+	radisGenresConfigFile    = radis + "_genres.yaml"
+	radisAliasesConfigFile   = radis + "_aliases.yaml"
+	radisPlaylistsConfigFile = radis + "_playlists.yaml"
+	xdgMainPath              = radis + "/" + radisMainConfigFile
+	xdgGenrePath             = radis + "/" + radisGenresConfigFile
+	xdgAliasPath             = radis + "/" + radisAliasesConfigFile
+	xdgPlaylistsPath         = radis + "/" + radisPlaylistsConfigFile
+	xdgPlaysPath             = radis + "/" + radis + "_plays.yaml"
 )
 
+// PlayIndexFile returns where play statistics are stored, creating it if necessary.
+func (c *Config) PlayIndexFile() (string, error) {
+	return xdg.Data.Ensure(xdgPlaysPath)
+}
+
 func (c *Config) getConfigPaths() (mainConfigFile string, genresConfigFile string, aliasesConfigFile string, err error) {
 	genresConfigFile, err = xdg.Config.Find(xdgGenrePath)
 	if err != nil {
@@ -74,27 +89,46 @@ func (c *Config) Load() (err error) {
 	if err = c.Paths.Load(mainConfigFile); err != nil {
 		return
 	}
+	if err = c.Mirror.Load(mainConfigFile); err != nil {
+		return
+	}
+	if err = c.MPD.Load(mainConfigFile); err != nil {
+		return
+	}
+	if err = c.FilePolicy.Load(mainConfigFile); err != nil {
+		return
+	}
+	if err = c.Rolling.Load(mainConfigFile); err != nil {
+		return
+	}
+	if err = c.Profiles.Load(mainConfigFile); err != nil {
+		return
+	}
 	if err = c.Aliases.Load(aliasesConfigFile); err != nil {
 		return
 	}
 	if err = c.Genres.Load(genresConfigFile); err != nil {
 		return
 	}
+	// smart playlists are optional
+	if playlistsConfigFile, err := xdg.Config.Find(xdgPlaylistsPath); err == nil {
+		return c.Playlists.Load(playlistsConfigFile)
+	}
 	return
 }
 
-// Write writes the configuration files back, after having ordered their contents.
-func (c *Config) Write() (err error) {
+// Write writes the configuration files back, keeping their comments.
+// Artists and aliases are sorted; genres and main aliases too, unless keepOrder is set.
+func (c *Config) Write(keepOrder bool) (err error) {
 	// find configuration files
 	_, genresConfigFile, aliasesConfigFile, err := c.getConfigPaths()
 	if err != nil {
 		return
 	}
-	// write ordered config files
-	if err = c.Aliases.Write(aliasesConfigFile); err != nil {
+	if err = c.Aliases.Write(aliasesConfigFile, keepOrder); err != nil {
 		return
 	}
-	if err = c.Genres.Write(genresConfigFile); err != nil {
+	if err = c.Genres.Write(genresConfigFile, keepOrder); err != nil {
 		return
 	}
 	return
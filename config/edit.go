@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"sort"
+)
+
+// insertSorted adds a string to a sorted list, which HasArtist and HasAlias rely on.
+func insertSorted(list []string, value string) []string {
+	i := sort.SearchStrings(list, value)
+	list = append(list, "")
+	copy(list[i+1:], list[i:])
+	list[i] = value
+	return list
+}
+
+// removeSorted removes a string from a sorted list.
+func removeSorted(list []string, value string) ([]string, bool) {
+	i := sort.SearchStrings(list, value)
+	if i == len(list) || list[i] != value {
+		return list, false
+	}
+	return append(list[:i], list[i+1:]...), true
+}
+
+// find returns the index of a genre, or -1.
+func (a *Genres) find(name string) int {
+	for i, g := range *a {
+		if g.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// genreOf returns the index of the genre containing an artist, or -1.
+func (a *Genres) genreOf(artist string) int {
+	for i, g := range *a {
+		if g.HasArtist(artist) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddArtist adds an artist to a genre, which is created if necessary.
+func (a *Genres) AddArtist(genre, artist string) error {
+	if i := a.genreOf(artist); i != -1 {
+		return errors.New(artist + " is already in " + (*a)[i].Name + ", move it instead.")
+	}
+	i := a.find(genre)
+	if i == -1 {
+		*a = append(*a, Genre{Name: genre})
+		sort.Sort(*a)
+		i = a.find(genre)
+	}
+	(*a)[i].Artists = insertSorted((*a)[i].Artists, artist)
+	return nil
+}
+
+// RemoveArtist removes an artist from a genre, and the genre if it is then empty.
+func (a *Genres) RemoveArtist(genre, artist string) error {
+	i := a.find(genre)
+	if i == -1 {
+		return errors.New("Unknown genre " + genre)
+	}
+	artists, ok := removeSorted((*a)[i].Artists, artist)
+	if !ok {
+		return errors.New(artist + " is not in " + genre)
+	}
+	(*a)[i].Artists = artists
+	if len(artists) == 0 {
+		*a = append((*a)[:i], (*a)[i+1:]...)
+	}
+	return nil
+}
+
+// MoveArtist moves an artist from its genre to another one.
+func (a *Genres) MoveArtist(genre, artist string) error {
+	i := a.genreOf(artist)
+	if i == -1 {
+		return errors.New(artist + " is not in any genre, add it instead.")
+	}
+	if (*a)[i].Name == genre {
+		return errors.New(artist + " is already in " + genre)
+	}
+	if err := a.RemoveArtist((*a)[i].Name, artist); err != nil {
+		return err
+	}
+	return a.AddArtist(genre, artist)
+}
+
+// Rename a genre, and its sub-genres with it.
+func (a *Genres) Rename(genre, newName string) error {
+	if IsWithin(newName, genre) {
+		return errors.New("Cannot rename " + genre + " to one of its sub-genres.")
+	}
+	renamed := make(map[int]string)
+	for i, g := range *a {
+		if IsWithin(g.Name, genre) {
+			renamed[i] = newName + g.Name[len(genre):]
+		}
+	}
+	if len(renamed) == 0 {
+		return errors.New("Unknown genre " + genre)
+	}
+	for _, name := range renamed {
+		if a.find(name) != -1 {
+			return errors.New("Genre " + name + " already exists.")
+		}
+	}
+	for i, name := range renamed {
+		(*a)[i].Name = name
+	}
+	sort.Sort(*a)
+	return nil
+}
+
+// AddAlias adds an alias to an artist, which is created if necessary.
+func (a *Aliases) AddAlias(mainAlias, alias string) error {
+	for _, artist := range *a {
+		if artist.HasAlias(alias) {
+			return errors.New(alias + " is already an alias of " + artist.MainAlias)
+		}
+		if artist.MainAlias == alias {
+			return errors.New(alias + " already has aliases of its own.")
+		}
+		if artist.HasAlias(mainAlias) {
+			return errors.New(mainAlias + " is an alias of " + artist.MainAlias + ", add the alias to it instead.")
+		}
+	}
+	for i := range *a {
+		if (*a)[i].MainAlias == mainAlias {
+			(*a)[i].Aliases = insertSorted((*a)[i].Aliases, alias)
+			return nil
+		}
+	}
+	*a = append(*a, Artist{MainAlias: mainAlias, Aliases: []string{alias}})
+	sort.Sort(*a)
+	return nil
+}
+
+// RemoveAlias removes an alias of an artist, and the artist if it has no aliases left.
+func (a *Aliases) RemoveAlias(mainAlias, alias string) error {
+	for i := range *a {
+		if (*a)[i].MainAlias != mainAlias {
+			continue
+		}
+		aliases, ok := removeSorted((*a)[i].Aliases, alias)
+		if !ok {
+			return errors.New(alias + " is not an alias of " + mainAlias)
+		}
+		(*a)[i].Aliases = aliases
+		if len(aliases) == 0 {
+			*a = append((*a)[:i], (*a)[i+1:]...)
+		}
+		return nil
+	}
+	return errors.New("Unknown artist " + mainAlias)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenresEdit(t *testing.T) {
+	g := Genres{
+		Genre{Name: "Jazz", Artists: []string{"Coltrane", "Miles"}},
+		Genre{Name: "Rock", Artists: []string{"Blur"}},
+	}
+	if err := g.AddArtist("Jazz", "Blur"); err == nil {
+		t.Errorf("AddArtist should not add an artist already in a genre")
+	}
+	if err := g.AddArtist("Blues", "Muddy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddArtist("Jazz", "Monk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveArtist("Jazz", "Blur"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RemoveArtist("Jazz", "Coltrane"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RemoveArtist("Jazz", "Coltrane"); err == nil {
+		t.Errorf("RemoveArtist should fail for an artist not in the genre")
+	}
+	if err := g.Rename("Blues", "Chicago Blues"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Rename("Jazz", "Chicago Blues"); err == nil {
+		t.Errorf("Rename should not merge genres")
+	}
+	expected := Genres{
+		Genre{Name: "Chicago Blues", Artists: []string{"Muddy"}},
+		Genre{Name: "Jazz", Artists: []string{"Blur", "Miles", "Monk"}},
+	}
+	if !reflect.DeepEqual(g, expected) {
+		t.Errorf("Genres edits returned %v, expected %v", g, expected)
+	}
+}
+
+func TestRenameSubGenres(t *testing.T) {
+	g := Genres{
+		Genre{Name: "Metal", Artists: []string{"Metallica"}},
+		Genre{Name: "Metal/Doom", Artists: []string{"Sleep"}},
+		Genre{Name: "Metal/Doom/Funeral", Artists: []string{"Skepticism"}},
+		Genre{Name: "Metal-Core", Artists: []string{"Converge"}},
+		Genre{Name: "Stoner/Doom", Artists: []string{"Kyuss"}},
+	}
+	if err := g.Rename("Metal", "Stoner"); err == nil {
+		t.Errorf("Rename should not merge sub-genres with existing ones")
+	}
+	if err := g.Rename("Metal", "Metal/Old"); err == nil {
+		t.Errorf("Rename should not move a genre into its own sub-genre")
+	}
+	if err := g.Rename("Metal", "Heavy Metal"); err != nil {
+		t.Fatal(err)
+	}
+	expected := Genres{
+		Genre{Name: "Heavy Metal", Artists: []string{"Metallica"}},
+		Genre{Name: "Heavy Metal/Doom", Artists: []string{"Sleep"}},
+		Genre{Name: "Heavy Metal/Doom/Funeral", Artists: []string{"Skepticism"}},
+		Genre{Name: "Metal-Core", Artists: []string{"Converge"}},
+		Genre{Name: "Stoner/Doom", Artists: []string{"Kyuss"}},
+	}
+	if !reflect.DeepEqual(g, expected) {
+		t.Errorf("Rename returned %v, expected %v", g, expected)
+	}
+}
+
+func TestAliasesEdit(t *testing.T) {
+	a := Aliases{Artist{MainAlias: "MF DOOM", Aliases: []string{"Viktor Vaughn"}}}
+	if err := a.AddAlias("MF DOOM", "JJ DOOM"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddAlias("Madlib", "Quasimoto"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddAlias("Madlib", "JJ DOOM"); err == nil {
+		t.Errorf("AddAlias should not give an alias to two artists")
+	}
+	if err := a.AddAlias("Madvillain", "Madlib"); err == nil {
+		t.Errorf("AddAlias should not make an artist with aliases the alias of another one")
+	}
+	if err := a.AddAlias("Viktor Vaughn", "King Geedorah"); err == nil {
+		t.Errorf("AddAlias should not give aliases to an alias")
+	}
+	if err := a.RemoveAlias("MF DOOM", "Viktor Vaughn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RemoveAlias("Madlib", "Quasimoto"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RemoveAlias("Madlib", "Quasimoto"); err == nil {
+		t.Errorf("RemoveAlias should fail for an unknown artist")
+	}
+	expected := Aliases{Artist{MainAlias: "MF DOOM", Aliases: []string{"JJ DOOM"}}}
+	if !reflect.DeepEqual(a, expected) {
+		t.Errorf("Aliases edits returned %v, expected %v", a, expected)
+	}
+}
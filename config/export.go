@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ExportProfile describes how playlist paths are written for another device.
+type ExportProfile struct {
+	// Destination is where exported playlists are written.
+	Destination string `yaml:"Destination"`
+	// Prefix is where the collection root is on the device, absolute or relative to the playlists.
+	Prefix string `yaml:"Prefix"`
+	// Absolute uses Paths.Root as prefix if Prefix is empty.
+	Absolute bool `yaml:"Absolute"`
+	// Separator is "/" by default.
+	Separator string `yaml:"Separator"`
+	// Extensions maps extensions of the collection to those on the device, for lossy mirrors.
+	Extensions map[string]string `yaml:"Extensions"`
+}
+
+func (e *ExportProfile) String() string {
+	txt := "Destination: " + e.Destination + ", Prefix: " + e.Prefix
+	txt += fmt.Sprintf(", Absolute: %v, Separator: %s", e.Absolute, e.separator())
+	extensions := []string{}
+	for from, to := range e.Extensions {
+		extensions = append(extensions, from+" -> "+to)
+	}
+	sort.Strings(extensions)
+	if len(extensions) != 0 {
+		txt += ", Extensions: " + strings.Join(extensions, ", ")
+	}
+	return txt + "\n"
+}
+
+func (e *ExportProfile) separator() string {
+	if e.Separator == "" {
+		return "/"
+	}
+	return e.Separator
+}
+
+// Translate converts a path relative to the collection root to its location on the device.
+// Locations used in URIs, as in xspf playlists, are always separated by "/".
+func (e *ExportProfile) Translate(root, relativePath string, inURI bool) string {
+	extension := filepath.Ext(relativePath)
+	for from, to := range e.Extensions {
+		if strings.EqualFold(from, extension) {
+			relativePath = strings.TrimSuffix(relativePath, extension) + to
+			break
+		}
+	}
+	prefix := e.Prefix
+	if prefix == "" && e.Absolute {
+		prefix = root
+	}
+	separator := e.separator()
+	if inURI {
+		if separator == `\` {
+			prefix = strings.Replace(prefix, `\`, "/", -1)
+		}
+		separator = "/"
+	}
+	parts := strings.Split(filepath.ToSlash(relativePath), "/")
+	if prefix != "" {
+		parts = append([]string{strings.TrimRight(prefix, "/\\")}, parts...)
+	}
+	return strings.Join(parts, separator)
+}
+
+// ExportProfiles are the named ExportProfile of the main configuration file.
+type ExportProfiles map[string]ExportProfile
+
+func (ep ExportProfiles) String() (txt string) {
+	txt = "Export profiles:\n"
+	names := []string{}
+	for name := range ep {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profile := ep[name]
+		txt += "\t" + name + ": " + profile.String()
+	}
+	return
+}
+
+// Load the ExportProfiles section of the main configuration file.
+func (ep *ExportProfiles) Load(path string) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	section := struct {
+		ExportProfiles ExportProfiles `yaml:"ExportProfiles"`
+	}{}
+	err = yaml.Unmarshal(data, &section)
+	if err != nil {
+		panic(err)
+	}
+	*ep = section.ExportProfiles
+	return
+}
@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+var testTranslate = []struct {
+	profile  ExportProfile
+	path     string
+	inURI    bool
+	expected string
+}{
+	{ExportProfile{}, "genre/a/a (2000) one/01.flac", false, "genre/a/a (2000) one/01.flac"},
+	{ExportProfile{Absolute: true}, "genre/a/a (2000) one/01.flac", false, "/music/genre/a/a (2000) one/01.flac"},
+	{ExportProfile{Prefix: "/sdcard/Music/", Extensions: map[string]string{".FLAC": ".opus"}}, "genre/a/a (2000) one/01.flac", false, "/sdcard/Music/genre/a/a (2000) one/01.opus"},
+	{ExportProfile{Prefix: `D:\Music`, Separator: `\`}, "genre/a/a (2000) one/01.flac", false, `D:\Music\genre\a\a (2000) one\01.flac`},
+	{ExportProfile{Prefix: `D:\Music`, Separator: `\`}, "genre/a/a (2000) one/01.flac", true, "D:/Music/genre/a/a (2000) one/01.flac"},
+	{ExportProfile{Prefix: "../Music", Extensions: map[string]string{".flac": ".opus"}}, "genre/a/a (2000) one/01.mp3", false, "../Music/genre/a/a (2000) one/01.mp3"},
+}
+
+func TestTranslate(t *testing.T) {
+	for _, tt := range testTranslate {
+		if v := tt.profile.Translate("/music", tt.path, tt.inURI); v != tt.expected {
+			t.Errorf("Translate(%s) with %s returned %s, expected %s", tt.path, tt.profile.String(), v, tt.expected)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package config
 
-import "sort"
+import (
+	"sort"
+	"strings"
+)
+
+// GenreSeparator separates a genre from its sub-genres, as in Metal/Doom, which is Root/Metal/Doom on disk.
+const GenreSeparator = "/"
 
 // Genre is a struct defining a genre and the artists that belong to it.
 type Genre struct {
@@ -8,6 +14,25 @@ type Genre struct {
 	Artists []string
 }
 
+// Depth returns 0 for a genre, 1 for its sub-genres, and so on.
+func (g *Genre) Depth() int {
+	return strings.Count(g.Name, GenreSeparator)
+}
+
+// IsWithin checks if a genre is a parent genre, or one of its sub-genres, ignoring case.
+func IsWithin(genre, parent string) bool {
+	genre, parent = strings.ToLower(genre), strings.ToLower(parent)
+	return genre == parent || strings.HasPrefix(genre, parent+GenreSeparator)
+}
+
+// parentGenre returns the genre containing a sub-genre, or an empty string.
+func parentGenre(genre string) string {
+	if i := strings.LastIndex(genre, GenreSeparator); i != -1 {
+		return genre[:i]
+	}
+	return ""
+}
+
 func (g *Genre) String() string {
 	txt := g.Name + ":\n"
 	for _, artist := range g.Artists {
@@ -29,7 +54,7 @@ func (g *Genre) HasArtist(artist string) bool {
 
 // HasCompilation checks if the Genre contains a compilation with a specific title.
 func (g *Genre) HasCompilation(title string) bool {
-	fullTitle := "Various Artists | " + title
+	fullTitle := compilationPrefix + title
 	// already sorted at Load
 	i := sort.SearchStrings(g.Artists, fullTitle)
 	if i < len(g.Artists) && g.Artists[i] == fullTitle {
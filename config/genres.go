@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"io/ioutil"
 	"sort"
 	"strings"
@@ -11,10 +12,26 @@ import (
 // Genres is a list of knows Genres and their artists.
 type Genres []Genre
 
+// String shows the genres as a tree, sub-genres under their parents.
 func (a *Genres) String() (text string) {
 	text = "All Genres: \n"
+	shown := make(map[string]bool)
 	for _, genre := range *a {
-		text += "\t" + genre.String()
+		parts := strings.Split(genre.Name, GenreSeparator)
+		for i := range parts {
+			name := strings.Join(parts[:i+1], GenreSeparator)
+			if shown[name] {
+				continue
+			}
+			shown[name] = true
+			indent := strings.Repeat("\t", i)
+			text += "\t" + indent + parts[i] + ":\n"
+			if i == len(parts)-1 {
+				for _, artist := range genre.Artists {
+					text += "\t\t" + indent + "- " + artist + "\n"
+				}
+			}
+		}
 	}
 	return
 }
@@ -23,8 +40,21 @@ func (a Genres) Len() int {
 	return len(a)
 }
 
+// Less keeps sub-genres right after their parent genre.
 func (a Genres) Less(i, j int) bool {
-	return strings.ToLower(a[i].Name) < strings.ToLower(a[j].Name)
+	return lessGenreNames(a[i].Name, a[j].Name)
+}
+
+// lessGenreNames compares genres level by level, ignoring case.
+func lessGenreNames(a, b string) bool {
+	left := strings.Split(strings.ToLower(a), GenreSeparator)
+	right := strings.Split(strings.ToLower(b), GenreSeparator)
+	for k := 0; k < len(left) && k < len(right); k++ {
+		if left[k] != right[k] {
+			return left[k] < right[k]
+		}
+	}
+	return len(left) < len(right)
 }
 
 func (a Genres) Swap(i, j int) {
@@ -38,33 +68,82 @@ func (a *Genres) Load(path string) (err error) {
 		panic(err)
 	}
 
-	m := make(map[string][]string)
+	m := make(map[string]interface{})
 	err = yaml.Unmarshal(data, &m)
 	if err != nil {
 		panic(err)
 	}
 
-	for genre := range m {
+	artists := make(map[string][]string)
+	for genre, entries := range m {
+		if err = addGenreEntries(artists, genre, entries); err != nil {
+			return
+		}
+	}
+	for genre := range artists {
 		var newGenre Genre
 		newGenre.Name = genre
-		sort.Strings(m[genre])
-		newGenre.Artists = m[genre]
+		sort.Strings(artists[genre])
+		newGenre.Artists = artists[genre]
 		*a = append(*a, newGenre)
 	}
 	sort.Sort(*a)
 	return
 }
 
-func (a *Genres) Write(path string) (err error) {
-	m := make(map[string][]string)
-	for _, genre := range *a {
-		m[genre.Name] = genre.Artists
+// addGenreEntries reads the entries of a genre, which are artists or sub-genres:
+//
+//	Metal:
+//	- Metallica
+//	- Doom:
+//	  - Candlemass
+//
+// A genre with no artists can also be a map of sub-genres, and sub-genres can be defined directly, as Metal/Doom.
+func addGenreEntries(artists map[string][]string, genre string, value interface{}) error {
+	genre = strings.Trim(genre, GenreSeparator)
+	var entries []interface{}
+	switch v := value.(type) {
+	case nil:
+	case []interface{}:
+		entries = v
+	case map[interface{}]interface{}:
+		entries = []interface{}{v}
+	default:
+		return errors.New("Genre " + genre + " must be a list.")
+	}
+	hasSubGenres := false
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case string:
+			artists[genre] = append(artists[genre], e)
+		case map[interface{}]interface{}:
+			hasSubGenres = true
+			for name, subEntries := range e {
+				subGenre, ok := name.(string)
+				if !ok {
+					return errors.New("Invalid sub-genre of " + genre)
+				}
+				if err := addGenreEntries(artists, genre+GenreSeparator+subGenre, subEntries); err != nil {
+					return err
+				}
+			}
+		default:
+			return errors.New("Invalid entry in genre " + genre)
+		}
 	}
+	// a genre only made of sub-genres has no artists of its own
+	if _, ok := artists[genre]; !ok && !hasSubGenres {
+		artists[genre] = []string{}
+	}
+	return nil
+}
 
-	d, err := yaml.Marshal(&m)
-	if err != nil {
-		return
+// Write the genres, keeping the comments of the file, and the order of its genres if keepOrder is set.
+func (a *Genres) Write(path string, keepOrder bool) (err error) {
+	lists := make(map[string][]string)
+	for _, genre := range *a {
+		lists[genre.Name] = genre.Artists
 	}
-	err = ioutil.WriteFile(path, d, 0777)
-	return
+	lf := listFile{lists: lists, nested: true, less: lessGenreNames}
+	return lf.write(path, keepOrder)
 }
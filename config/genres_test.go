@@ -0,0 +1,85 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenresLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "radis_genres.yaml")
+	content := `Metal:
+- Metallica
+- Doom:
+  - Sleep
+  - Candlemass
+Metal/Sludge:
+- Melvins
+Metal-Core:
+- Converge
+Electronic:
+  Ambient:
+  - Eno
+Jazz:
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var g Genres
+	if err := g.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	expected := Genres{
+		Genre{Name: "Electronic/Ambient", Artists: []string{"Eno"}},
+		Genre{Name: "Jazz", Artists: []string{}},
+		Genre{Name: "Metal", Artists: []string{"Metallica"}},
+		Genre{Name: "Metal/Doom", Artists: []string{"Candlemass", "Sleep"}},
+		Genre{Name: "Metal/Sludge", Artists: []string{"Melvins"}},
+		Genre{Name: "Metal-Core", Artists: []string{"Converge"}},
+	}
+	if !reflect.DeepEqual(g, expected) {
+		t.Errorf("Load(%s) returned %v, expected %v", path, g, expected)
+	}
+
+	tree := "All Genres: \n" +
+		"\tElectronic:\n\t\tAmbient:\n\t\t\t- Eno\n" +
+		"\tJazz:\n" +
+		"\tMetal:\n\t\t- Metallica\n\t\tDoom:\n\t\t\t- Candlemass\n\t\t\t- Sleep\n\t\tSludge:\n\t\t\t- Melvins\n" +
+		"\tMetal-Core:\n\t\t- Converge\n"
+	if v := g.String(); v != tree {
+		t.Errorf("String returned:\n%s\nexpected:\n%s", v, tree)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("Metal:\n- Doom: Sleep\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	g = Genres{}
+	if err := g.Load(path); err == nil {
+		t.Errorf("Load(%s) should have rejected a sub-genre that is not a list", path)
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	for _, tc := range []struct {
+		genre, parent string
+		expected      bool
+	}{
+		{"Metal", "Metal", true},
+		{"Metal/Doom", "metal", true},
+		{"Metal/Doom/Drone", "Metal/Doom", true},
+		{"Metal-Core", "Metal", false},
+		{"Metal", "Metal/Doom", false},
+	} {
+		if v := IsWithin(tc.genre, tc.parent); v != tc.expected {
+			t.Errorf("IsWithin(%s, %s) returned %v, expected %v", tc.genre, tc.parent, v, tc.expected)
+		}
+	}
+}
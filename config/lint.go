@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severities of the findings of Lint.
+const (
+	// SeverityError is for configurations where albums do not go where expected.
+	SeverityError = "error"
+	// SeverityWarning is for configurations that are probably mistakes.
+	SeverityWarning = "warning"
+	// SeverityInfo is for configurations that are harmless but could be cleaned up.
+	SeverityInfo = "info"
+)
+
+// compilationPrefix starts the entries of genres that are compilations rather than artists.
+const compilationPrefix = "Various Artists | "
+
+// Finding is a problem found by Lint.
+type Finding struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Message  string `json:"message"`
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, f.File, f.Message)
+}
+
+// Lint checks the genres and aliases for contradictions and mistakes, and compares them with the collection.
+func (c *Config) Lint() (findings []Finding) {
+	findings = []Finding{}
+	add := func(severity, file, format string, a ...interface{}) {
+		findings = append(findings, Finding{Severity: severity, File: file, Message: fmt.Sprintf(format, a...)})
+	}
+
+	// paths
+	if err := c.Check(); err != nil {
+		add(SeverityError, radisMainConfigFile, "%s", err.Error())
+	}
+
+	// aliases
+	mainAliases := make(map[string]bool)
+	for _, a := range c.Aliases {
+		mainAliases[a.MainAlias] = true
+	}
+	aliasOf := make(map[string]string)
+	for _, a := range c.Aliases {
+		for _, alias := range a.Aliases {
+			if mainAliases[alias] && alias != a.MainAlias {
+				add(SeverityError, radisAliasesConfigFile, "%s is an alias of %s, and has aliases of its own", alias, a.MainAlias)
+			}
+			if other, ok := aliasOf[alias]; ok && other != a.MainAlias {
+				add(SeverityError, radisAliasesConfigFile, "%s is an alias of both %s and %s", alias, other, a.MainAlias)
+				continue
+			}
+			aliasOf[alias] = a.MainAlias
+		}
+	}
+
+	// genres
+	genreOf := make(map[string]string)
+	for _, g := range c.Genres {
+		if problem := c.checkGenreName(g.Name); problem != "" {
+			add(SeverityError, radisGenresConfigFile, "genre %q %s", g.Name, problem)
+		}
+		if len(g.Artists) == 0 {
+			add(SeverityWarning, radisGenresConfigFile, "genre %s is empty", g.Name)
+		}
+		// the directory of a sub-genre is next to the directories of the artists of its parent
+		if parent := parentGenre(g.Name); parent != "" {
+			for _, p := range c.Genres {
+				if p.Name == parent && p.HasArtist(g.Name[len(parent)+1:]) {
+					add(SeverityError, radisGenresConfigFile, "%s is both an artist of %s and one of its sub-genres", g.Name[len(parent)+1:], parent)
+				}
+			}
+		}
+		for _, artist := range g.Artists {
+			if other, ok := genreOf[artist]; ok {
+				if IsWithin(g.Name, other) {
+					// sorted genres come before their sub-genres
+					add(SeverityInfo, radisGenresConfigFile, "%s is in both %s and %s, %s is used", artist, other, g.Name, g.Name)
+				} else {
+					add(SeverityError, radisGenresConfigFile, "%s is in both %s and %s", artist, other, g.Name)
+				}
+				continue
+			}
+			genreOf[artist] = g.Name
+			if main, ok := aliasOf[artist]; ok {
+				add(SeverityWarning, radisGenresConfigFile, "%s in genre %s is an alias of %s, which should be listed instead", artist, g.Name, main)
+				continue
+			}
+			if !c.hasAlbumsOnDisk(g.Name, artist) {
+				add(SeverityInfo, radisGenresConfigFile, "%s in genre %s has no albums in %s", artist, g.Name, c.Paths.Root)
+			}
+		}
+	}
+
+	order := map[string]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return order[findings[i].Severity] < order[findings[j].Severity]
+	})
+	return
+}
+
+// checkGenreName returns why a genre cannot be used as a directory name, or an empty string.
+// Each level of a sub-genre is a directory.
+func (c *Config) checkGenreName(name string) string {
+	parts := strings.Split(name, GenreSeparator)
+	if parts[0] == c.Paths.IncomingSubdir || parts[0] == c.Paths.UnsortedSubdir {
+		return "is used by radis for incoming or unsorted albums"
+	}
+	for _, part := range parts {
+		if problem := checkDirectoryName(part); problem != "" {
+			return problem
+		}
+	}
+	return ""
+}
+
+// checkDirectoryName returns why a name cannot be used as a directory name, or an empty string.
+func checkDirectoryName(name string) string {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return "is empty"
+	case name == "." || name == "..":
+		return "is not a directory name"
+	case strings.Contains(name, "\x00"):
+		return "contains characters not allowed in directory names"
+	case strings.TrimSpace(name) != name:
+		return "starts or ends with spaces"
+	}
+	return ""
+}
+
+// hasAlbumsOnDisk checks if an artist, or a compilation, of a genre has albums in the collection.
+func (c *Config) hasAlbumsOnDisk(genre, artist string) bool {
+	title := ""
+	if strings.HasPrefix(artist, compilationPrefix) {
+		title = strings.TrimPrefix(artist, compilationPrefix)
+		artist = "Various Artists"
+	}
+	contents, err := ioutil.ReadDir(filepath.Join(c.Paths.Root, filepath.FromSlash(genre), artist))
+	if err != nil {
+		return false
+	}
+	for _, fileInfo := range contents {
+		if !fileInfo.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(fileInfo.Name(), "[MP3]")
+		if title == "" || strings.HasSuffix(name, ") "+title) {
+			return true
+		}
+	}
+	return false
+}
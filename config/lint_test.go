@@ -0,0 +1,78 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, album := range []string{
+		"Rock/Radiohead/Radiohead (1997) OK Computer",
+		"Blues/Various Artists/Various Artists (1990) Rare Chicago Blues[MP3]",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, album), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := Config{
+		Paths: Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: filepath.Join(dir, "playlists")},
+		Aliases: Aliases{
+			Artist{MainAlias: "MF DOOM", Aliases: []string{"Madvillain", "Viktor Vaughn"}},
+			Artist{MainAlias: "Madvillain", Aliases: []string{"Madlib"}},
+			Artist{MainAlias: "Quasimoto", Aliases: []string{"Madlib"}},
+		},
+		Genres: Genres{
+			Genre{Name: "Blues", Artists: []string{"Various Artists | Lost Blues", "Various Artists | Rare Chicago Blues"}},
+			Genre{Name: "Hip-Hop", Artists: []string{"Viktor Vaughn"}},
+			Genre{Name: "Jazz"},
+			Genre{Name: "Metal", Artists: []string{"Doom", "Sleep"}},
+			Genre{Name: "Metal/Doom", Artists: []string{"Sleep"}},
+			Genre{Name: "Metal/ Sludge", Artists: []string{"Melvins"}},
+			Genre{Name: "Rock", Artists: []string{"Radiohead"}},
+			Genre{Name: "UNCATEGORIZED", Artists: []string{"Radiohead"}},
+		},
+	}
+	expected := []string{
+		"[error] radis.yaml: Path " + filepath.Join(dir, "playlists") + " does not exist!!!",
+		"[error] radis_aliases.yaml: Madvillain is an alias of MF DOOM, and has aliases of its own",
+		"[error] radis_aliases.yaml: Madlib is an alias of both Madvillain and Quasimoto",
+		"[error] radis_genres.yaml: Doom is both an artist of Metal and one of its sub-genres",
+		`[error] radis_genres.yaml: genre "Metal/ Sludge" starts or ends with spaces`,
+		`[error] radis_genres.yaml: genre "UNCATEGORIZED" is used by radis for incoming or unsorted albums`,
+		"[error] radis_genres.yaml: Radiohead is in both Rock and UNCATEGORIZED",
+		"[warning] radis_genres.yaml: Viktor Vaughn in genre Hip-Hop is an alias of MF DOOM, which should be listed instead",
+		"[warning] radis_genres.yaml: genre Jazz is empty",
+		"[info] radis_genres.yaml: Various Artists | Lost Blues in genre Blues has no albums in " + dir,
+		"[info] radis_genres.yaml: Doom in genre Metal has no albums in " + dir,
+		"[info] radis_genres.yaml: Sleep in genre Metal has no albums in " + dir,
+		"[info] radis_genres.yaml: Sleep is in both Metal and Metal/Doom, Metal/Doom is used",
+		"[info] radis_genres.yaml: Melvins in genre Metal/ Sludge has no albums in " + dir,
+	}
+	findings := []string{}
+	for _, f := range c.Lint() {
+		findings = append(findings, f.String())
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("Lint returned:\n%v\nexpected:\n%v", findings, expected)
+	}
+
+	// nothing to report
+	c = Config{Paths: Paths{Root: dir, MPDPlaylistDirectory: dir}}
+	data, err := json.Marshal(c.Lint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[]" {
+		t.Errorf("Lint returned %s, expected no findings", string(data))
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	inputPlaceholder  = "{input}"
+	outputPlaceholder = "{output}"
+)
+
+// Mirror describes the lossy copy of the collection kept for portable devices.
+type Mirror struct {
+	Genres    []string `yaml:"Genres"`
+	Encoder   string   `yaml:"Encoder"`
+	Extension string   `yaml:"Extension"`
+}
+
+func (m *Mirror) String() string {
+	txt := "Mirror configuration:\n"
+	txt += "\tGenres: " + strings.Join(m.Genres, ", ") + "\n"
+	txt += "\tEncoder: " + m.Encoder + "\n"
+	txt += "\tExtension: " + m.Extension + "\n"
+	return txt
+}
+
+// Load the Mirror section of the main configuration file.
+func (m *Mirror) Load(path string) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	section := struct {
+		Mirror Mirror `yaml:"Mirror"`
+	}{}
+	err = yaml.Unmarshal(data, &section)
+	if err != nil {
+		panic(err)
+	}
+	*m = section.Mirror
+	if m.Extension != "" && !strings.HasPrefix(m.Extension, ".") {
+		m.Extension = "." + m.Extension
+	}
+	return
+}
+
+// HasGenre checks if a genre must be mirrored, with the sub-genres of the selected genres.
+// All genres are mirrored if none are selected.
+func (m *Mirror) HasGenre(genre string) bool {
+	if len(m.Genres) == 0 {
+		return true
+	}
+	for _, g := range m.Genres {
+		if IsWithin(genre, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncoderCommand returns the encoder command line for a given input and output file.
+func (m *Mirror) EncoderCommand(input, output string) (command []string) {
+	for _, part := range strings.Fields(m.Encoder) {
+		part = strings.Replace(part, inputPlaceholder, input, -1)
+		part = strings.Replace(part, outputPlaceholder, output, -1)
+		command = append(command, part)
+	}
+	return
+}
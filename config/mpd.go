@@ -0,0 +1,78 @@
+package config
+
+import (
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MPD describes how to reach the MPD server that plays the collection.
+type MPD struct {
+	// Network is "tcp" or "unix", "tcp" by default.
+	Network  string `yaml:"Network"`
+	Address  string `yaml:"Address"`
+	Password string `yaml:"Password"`
+	// UpdateTimeout is how long to wait for database updates, in seconds.
+	UpdateTimeout int `yaml:"UpdateTimeout"`
+	// LogFile is the mpd.log where played songs are found.
+	LogFile string `yaml:"LogFile"`
+	// PlayCountSticker and RatingSticker are the song stickers set by MPD clients.
+	PlayCountSticker string `yaml:"PlayCountSticker"`
+	RatingSticker    string `yaml:"RatingSticker"`
+}
+
+func (m *MPD) String() string {
+	txt := "MPD:\n"
+	txt += "\tNetwork: " + m.Network + "\n"
+	txt += "\tAddress: " + m.Address + "\n"
+	if m.Password != "" {
+		txt += "\tPassword: ********\n"
+	}
+	txt += "\tUpdateTimeout: " + m.Timeout().String() + "\n"
+	if m.LogFile != "" {
+		txt += "\tLogFile: " + m.LogFile + "\n"
+	}
+	txt += "\tStickers: " + m.PlayCountSticker + ", " + m.RatingSticker + "\n"
+	return txt
+}
+
+// IsConfigured indicates if radis should talk to MPD.
+func (m *MPD) IsConfigured() bool {
+	return m.Address != ""
+}
+
+// Timeout returns how long to wait for database updates.
+func (m *MPD) Timeout() time.Duration {
+	if m.UpdateTimeout <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(m.UpdateTimeout) * time.Second
+}
+
+// Load the MPD section of the main configuration file.
+func (m *MPD) Load(path string) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	section := struct {
+		MPD MPD `yaml:"MPD"`
+	}{}
+	err = yaml.Unmarshal(data, &section)
+	if err != nil {
+		panic(err)
+	}
+	*m = section.MPD
+	if m.Network == "" {
+		m.Network = "tcp"
+	}
+	if m.PlayCountSticker == "" {
+		m.PlayCountSticker = "playcount"
+	}
+	if m.RatingSticker == "" {
+		m.RatingSticker = "rating"
+	}
+	return
+}
@@ -44,12 +44,17 @@ func (mc *Paths) Load(path string) (err error) {
 		panic(err)
 	}
 
-	m := make(map[string]string)
+	m := make(map[string]interface{})
 	err = yaml.Unmarshal(data, &m)
 	if err != nil {
 		panic(err)
 	}
-	for k, v := range m {
+	for k, value := range m {
+		// other sections of radis.yaml are loaded elsewhere
+		v, ok := value.(string)
+		if !ok {
+			continue
+		}
 		// TODO check that we have all keys!!!
 		switch k {
 		case "Root":
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Orders of playlists.
+const (
+	OrderYear    = "year"
+	OrderArtist  = "artist"
+	OrderGenre   = "genre"
+	OrderAdded   = "added"
+	OrderShuffle = "shuffle"
+)
+
+// Orders lists the ways playlists can be ordered.
+var Orders = []string{OrderYear, OrderArtist, OrderGenre, OrderAdded, OrderShuffle}
+
+// IsValidOrder checks if playlists can be ordered in a given way.
+func IsValidOrder(order string) bool {
+	for _, o := range Orders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
+
+// Formats a SmartPlaylist can select.
+const (
+	FormatFLAC = "flac"
+	FormatMP3  = "mp3"
+)
+
+// stringList can be written in yaml as a single string or as a list.
+type stringList []string
+
+// UnmarshalYAML accepts both "genre: Jazz" and "genre: [Jazz, Blues]".
+func (s *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*s = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// smartPlaylistRules is how a SmartPlaylist is described in radis_playlists.yaml.
+type smartPlaylistRules struct {
+	Genre   stringList `yaml:"genre"`
+	Artist  stringList `yaml:"artist"`
+	Year    string     `yaml:"year"`
+	Format  string     `yaml:"format"`
+	Added   string     `yaml:"added"`
+	Order   string     `yaml:"order"`
+	Reverse bool       `yaml:"reverse"`
+	Seed    int64      `yaml:"seed"`
+	// play statistics
+	Plays    string `yaml:"plays"`
+	Played   string `yaml:"played"`
+	Unplayed string `yaml:"unplayed"`
+	Rating   int    `yaml:"rating"`
+}
+
+// SmartPlaylist is a playlist defined by rules, regenerated from the collection.
+// Empty rules select everything.
+// Albums are in collection order, unless Order is set; a Seed makes shuffles reproducible.
+type SmartPlaylist struct {
+	Name        string
+	Genres      []string
+	Artists     []string
+	FromYear    int
+	ToYear      int
+	Format      string
+	AddedWithin time.Duration
+	Order       string
+	Reverse     bool
+	Seed        int64
+	// CountsPlays is set if albums are selected by play count, between FromPlays and ToPlays.
+	// ToPlays is -1 if there is no upper bound.
+	CountsPlays     bool
+	FromPlays       int
+	ToPlays         int
+	PlayedWithin    time.Duration
+	NotPlayedWithin time.Duration
+	MinRating       int
+}
+
+func (s *SmartPlaylist) String() string {
+	rules := []string{}
+	if len(s.Genres) != 0 {
+		rules = append(rules, "genre in ["+strings.Join(s.Genres, ", ")+"]")
+	}
+	if len(s.Artists) != 0 {
+		rules = append(rules, "artist in ["+strings.Join(s.Artists, ", ")+"]")
+	}
+	if s.FromYear != 0 || s.ToYear != 0 {
+		from, to := "", ""
+		if s.FromYear != 0 {
+			from = strconv.Itoa(s.FromYear)
+		}
+		if s.ToYear != 0 {
+			to = strconv.Itoa(s.ToYear)
+		}
+		rules = append(rules, "year: "+from+".."+to)
+	}
+	if s.Format != "" {
+		rules = append(rules, "format: "+s.Format)
+	}
+	if s.AddedWithin != 0 {
+		rules = append(rules, "added within "+s.AddedWithin.String())
+	}
+	if s.CountsPlays {
+		to := ""
+		if s.ToPlays != -1 {
+			to = strconv.Itoa(s.ToPlays)
+		}
+		rules = append(rules, "plays: "+strconv.Itoa(s.FromPlays)+".."+to)
+	}
+	if s.PlayedWithin != 0 {
+		rules = append(rules, "played within "+s.PlayedWithin.String())
+	}
+	if s.NotPlayedWithin != 0 {
+		rules = append(rules, "not played within "+s.NotPlayedWithin.String())
+	}
+	if s.MinRating != 0 {
+		rules = append(rules, "rating >= "+strconv.Itoa(s.MinRating))
+	}
+	if s.Order != "" {
+		rules = append(rules, "order: "+s.Order)
+	}
+	return s.Name + ": " + strings.Join(rules, ", ") + "\n"
+}
+
+// HasGenre checks if albums of a genre belong to the playlist, including the sub-genres of its genres.
+func (s *SmartPlaylist) HasGenre(genre string) bool {
+	if len(s.Genres) == 0 {
+		return true
+	}
+	for _, g := range s.Genres {
+		if IsWithin(genre, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasArtist checks if albums of an artist belong to the playlist.
+func (s *SmartPlaylist) HasArtist(artist string) bool {
+	return len(s.Artists) == 0 || hasString(s.Artists, artist)
+}
+
+// HasYear checks if albums released in a given year belong to the playlist.
+func (s *SmartPlaylist) HasYear(year int) bool {
+	return (s.FromYear == 0 || year >= s.FromYear) && (s.ToYear == 0 || year <= s.ToYear)
+}
+
+// HasFormat checks if albums in a given format belong to the playlist.
+func (s *SmartPlaylist) HasFormat(format string) bool {
+	return s.Format == "" || s.Format == format
+}
+
+// IsRecent checks if an album added at a given time belongs to the playlist.
+func (s *SmartPlaylist) IsRecent(added time.Time) bool {
+	return s.AddedWithin == 0 || time.Since(added) <= s.AddedWithin
+}
+
+// UsesPlays checks if the playlist depends on play statistics.
+func (s *SmartPlaylist) UsesPlays() bool {
+	return s.CountsPlays || s.PlayedWithin != 0 || s.NotPlayedWithin != 0 || s.MinRating != 0
+}
+
+// HasPlayCount checks if albums played a number of times belong to the playlist.
+func (s *SmartPlaylist) HasPlayCount(plays int) bool {
+	return !s.CountsPlays || (plays >= s.FromPlays && (s.ToPlays == -1 || plays <= s.ToPlays))
+}
+
+// HasLastPlayed checks if albums last played at a given time belong to the playlist.
+// lastPlayed is zero for albums that were never played.
+func (s *SmartPlaylist) HasLastPlayed(lastPlayed time.Time) bool {
+	if s.PlayedWithin != 0 && (lastPlayed.IsZero() || time.Since(lastPlayed) > s.PlayedWithin) {
+		return false
+	}
+	return s.NotPlayedWithin == 0 || lastPlayed.IsZero() || time.Since(lastPlayed) > s.NotPlayedWithin
+}
+
+// HasRating checks if albums with a given rating, out of 10, belong to the playlist.
+func (s *SmartPlaylist) HasRating(rating int) bool {
+	return rating >= s.MinRating
+}
+
+// hasString checks if a list contains a string, ignoring case.
+func hasString(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseYears parses "1960", "1955..1965", "1955.." or "..1965".
+func parseYears(years string) (from int, to int, err error) {
+	if years == "" {
+		return
+	}
+	parts := strings.SplitN(years, "..", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+	if parts[0] != "" {
+		if from, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+			return 0, 0, errors.New("Invalid year range: " + years)
+		}
+	}
+	if parts[1] != "" {
+		if to, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, errors.New("Invalid year range: " + years)
+		}
+	}
+	if to != 0 && from > to {
+		return 0, 0, errors.New("Invalid year range: " + years)
+	}
+	return
+}
+
+// parsePlays parses "never", "3", "2.." or "..5"; to is -1 if there is no upper bound.
+func parsePlays(plays string) (from int, to int, err error) {
+	plays = strings.TrimSpace(plays)
+	if plays == "never" {
+		return 0, 0, nil
+	}
+	parts := strings.SplitN(plays, "..", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+	to = -1
+	if parts[0] != "" {
+		if from, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil || from < 0 {
+			return 0, 0, errors.New("Invalid play count: " + plays)
+		}
+	}
+	if parts[1] != "" {
+		if to, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil || to < from {
+			return 0, 0, errors.New("Invalid play count: " + plays)
+		}
+	}
+	return
+}
+
+// ParseAge parses durations such as "30d", "2w", "1y", or anything time.ParseDuration understands.
+func ParseAge(age string) (duration time.Duration, err error) {
+	age = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(age), "within"))
+	if age == "" {
+		return
+	}
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour}
+	if unit, ok := units[age[len(age)-1:]]; ok {
+		n, err := strconv.Atoi(age[:len(age)-1])
+		if err != nil {
+			return 0, errors.New("Invalid duration: " + age)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	if duration, err = time.ParseDuration(age); err != nil {
+		return 0, errors.New("Invalid duration: " + age)
+	}
+	return
+}
+
+// newSmartPlaylist checks the rules of a playlist.
+func newSmartPlaylist(name string, rules smartPlaylistRules) (s SmartPlaylist, err error) {
+	s = SmartPlaylist{Name: name, Genres: rules.Genre, Artists: rules.Artist}
+	if s.FromYear, s.ToYear, err = parseYears(rules.Year); err != nil {
+		return
+	}
+	s.Format = strings.ToLower(rules.Format)
+	if s.Format != "" && s.Format != FormatFLAC && s.Format != FormatMP3 {
+		return s, errors.New("Invalid format for playlist " + name + ": " + rules.Format)
+	}
+	if rules.Order != "" && !IsValidOrder(rules.Order) {
+		return s, errors.New("Invalid order for playlist " + name + ": " + rules.Order)
+	}
+	s.Order, s.Reverse, s.Seed = rules.Order, rules.Reverse, rules.Seed
+	if s.AddedWithin, err = ParseAge(rules.Added); err != nil {
+		return
+	}
+	if rules.Plays != "" {
+		s.CountsPlays = true
+		if s.FromPlays, s.ToPlays, err = parsePlays(rules.Plays); err != nil {
+			return
+		}
+	}
+	if s.PlayedWithin, err = ParseAge(rules.Played); err != nil {
+		return
+	}
+	if s.NotPlayedWithin, err = ParseAge(rules.Unplayed); err != nil {
+		return
+	}
+	if rules.Rating < 0 || rules.Rating > 10 {
+		return s, errors.New("Invalid rating for playlist " + name + ", it must be between 0 and 10.")
+	}
+	s.MinRating = rules.Rating
+	return
+}
+
+// SmartPlaylists is the list of playlists defined in radis_playlists.yaml.
+type SmartPlaylists []SmartPlaylist
+
+func (a *SmartPlaylists) String() (text string) {
+	text = "Smart playlists: \n"
+	for _, s := range *a {
+		text += "\t" + s.String()
+	}
+	return
+}
+
+// Load the configuration file where smart playlists are defined.
+func (a *SmartPlaylists) Load(path string) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	m := make(map[string]smartPlaylistRules)
+	err = yaml.Unmarshal(data, &m)
+	if err != nil {
+		panic(err)
+	}
+
+	names := []string{}
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s, err := newSmartPlaylist(name, m[name])
+		if err != nil {
+			return err
+		}
+		*a = append(*a, s)
+	}
+	return
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testYears = []struct {
+	years        string
+	expectedFrom int
+	expectedTo   int
+	expectedErr  bool
+}{
+	{"", 0, 0, false},
+	{"1960", 1960, 1960, false},
+	{"1955..1965", 1955, 1965, false},
+	{"1955..", 1955, 0, false},
+	{"..1965", 0, 1965, false},
+	{"1965..1955", 0, 0, true},
+	{"sixties", 0, 0, true},
+}
+
+func TestParseYears(t *testing.T) {
+	for _, ty := range testYears {
+		from, to, err := parseYears(ty.years)
+		if from != ty.expectedFrom || to != ty.expectedTo || (err != nil) != ty.expectedErr {
+			t.Errorf("parseYears(%s) returned %d, %d, %v", ty.years, from, to, err)
+		}
+	}
+}
+
+var testAges = []struct {
+	age         string
+	expected    time.Duration
+	expectedErr bool
+}{
+	{"", 0, false},
+	{"30d", 30 * 24 * time.Hour, false},
+	{"within 2w", 14 * 24 * time.Hour, false},
+	{"12h", 12 * time.Hour, false},
+	{"1y", 365 * 24 * time.Hour, false},
+	{"a while", 0, true},
+}
+
+func TestParseAge(t *testing.T) {
+	for _, ta := range testAges {
+		if v, err := ParseAge(ta.age); v != ta.expected || (err != nil) != ta.expectedErr {
+			t.Errorf("ParseAge(%s) returned %s, %v, expected %s", ta.age, v, err, ta.expected)
+		}
+	}
+}
+
+var testPlays = []struct {
+	plays        string
+	expectedFrom int
+	expectedTo   int
+	expectedErr  bool
+}{
+	{"never", 0, 0, false},
+	{"3", 3, 3, false},
+	{"2..", 2, -1, false},
+	{"..5", 0, 5, false},
+	{"5..2", 0, 0, true},
+	{"often", 0, 0, true},
+}
+
+func TestParsePlays(t *testing.T) {
+	for _, tp := range testPlays {
+		from, to, err := parsePlays(tp.plays)
+		if from != tp.expectedFrom || to != tp.expectedTo || (err != nil) != tp.expectedErr {
+			t.Errorf("parsePlays(%s) returned %d, %d, %v", tp.plays, from, to, err)
+		}
+	}
+}
+
+func TestSmartPlaylistPlays(t *testing.T) {
+	s, err := newSmartPlaylist("forgotten", smartPlaylistRules{Plays: "1..", Unplayed: "1y", Rating: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.UsesPlays() || s.HasPlayCount(0) || !s.HasPlayCount(12) || s.HasRating(4) || !s.HasRating(8) {
+		t.Errorf("newSmartPlaylist returned unexpected rules: %s", s.String())
+	}
+	if s.HasLastPlayed(time.Now().Add(-time.Hour)) || !s.HasLastPlayed(time.Now().AddDate(-2, 0, 0)) {
+		t.Errorf("newSmartPlaylist returned unexpected rules: %s", s.String())
+	}
+	s, err = newSmartPlaylist("recent", smartPlaylistRules{Played: "2w"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasLastPlayed(time.Now().Add(-time.Hour)) || s.HasLastPlayed(time.Time{}) || !s.HasPlayCount(0) {
+		t.Errorf("newSmartPlaylist returned unexpected rules: %s", s.String())
+	}
+	if _, err = newSmartPlaylist("broken", smartPlaylistRules{Rating: 11}); err == nil {
+		t.Errorf("newSmartPlaylist should have rejected a rating of 11")
+	}
+}
+
+func TestSmartPlaylistsLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "radis_playlists.yaml")
+	content := "Cool Jazz:\n  genre: Jazz\n  year: 1955..1965\n" +
+		"Favourites:\n  artist: [Radiohead, MF DOOM]\n  format: FLAC\n  added: 30d\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var s SmartPlaylists
+	if err := s.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 2 {
+		t.Fatalf("Load(%s) returned %d playlists, expected 2", path, len(s))
+	}
+	jazz, favourites := s[0], s[1]
+	if !jazz.HasGenre("jazz") || jazz.HasGenre("Blues") || !jazz.HasYear(1959) || jazz.HasYear(1970) || !jazz.HasFormat(FormatMP3) {
+		t.Errorf("Load(%s) returned unexpected rules: %s", path, jazz.String())
+	}
+	if !favourites.HasArtist("MF DOOM") || favourites.HasArtist("Blur") || favourites.HasFormat(FormatMP3) ||
+		!favourites.IsRecent(time.Now().Add(-time.Hour)) || favourites.IsRecent(time.Now().Add(-60*24*time.Hour)) {
+		t.Errorf("Load(%s) returned unexpected rules: %s", path, favourites.String())
+	}
+
+	content = "Broken:\n  format: ogg\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s = SmartPlaylists{}
+	if err := s.Load(path); err == nil {
+		t.Errorf("Load(%s) should have rejected format ogg", path)
+	}
+}
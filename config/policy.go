@@ -0,0 +1,149 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// File categories, as used in the FilePolicy section of radis.yaml.
+const (
+	AudioLossless = "audio-lossless"
+	AudioLossy    = "audio-lossy"
+	Artwork       = "artwork"
+	RipMetadata   = "rip-metadata"
+	Junk          = "junk"
+	Forbidden     = "forbidden"
+	Unknown       = "unknown"
+)
+
+// FileCategories lists the categories in the order they are checked and displayed.
+var FileCategories = []string{AudioLossless, AudioLossy, Artwork, RipMetadata, Junk, Forbidden}
+
+// FileTypes maps file categories to lists of extensions.
+type FileTypes map[string][]string
+
+// category returns the category of an extension, if known.
+func (f FileTypes) category(extension string) (string, bool) {
+	for _, category := range FileCategories {
+		for _, e := range f[category] {
+			if strings.ToLower(e) == extension {
+				return category, true
+			}
+		}
+	}
+	return "", false
+}
+
+// remove an extension from the categories containing it.
+func (f FileTypes) remove(extension string) {
+	for category, extensions := range f {
+		for i, e := range extensions {
+			if strings.EqualFold(e, extension) {
+				f[category] = append(extensions[:i:i], extensions[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (f FileTypes) String() (txt string) {
+	for _, category := range FileCategories {
+		if len(f[category]) != 0 {
+			txt += "\t\t" + category + ": " + strings.Join(f[category], ", ") + "\n"
+		}
+	}
+	return
+}
+
+// FilePolicy classifies the files found in album directories, with optional rules for specific genres.
+type FilePolicy struct {
+	Default FileTypes            `yaml:"Default"`
+	Genres  map[string]FileTypes `yaml:"Genres"`
+}
+
+// DefaultFilePolicy is used for what the FilePolicy section of radis.yaml does not define.
+func DefaultFilePolicy() FilePolicy {
+	return FilePolicy{
+		Default: FileTypes{
+			AudioLossless: {".flac"},
+			AudioLossy:    {".mp3", ".wma", ".m4a", ".aac", ".ogg", ".opus"},
+			Artwork:       {".jpg", ".jpeg", ".png"},
+			RipMetadata:   {".log", ".cue", ".txt", ".m3u", ".m3u8", ".accurip", ".nfo", ".sfv"},
+			Junk:          {".db", ".ini", ".DS_Store"},
+		},
+	}
+}
+
+func (p *FilePolicy) String() string {
+	txt := "File policy:\n\tDefault:\n" + p.Default.String()
+	genres := []string{}
+	for genre := range p.Genres {
+		genres = append(genres, genre)
+	}
+	sort.Strings(genres)
+	for _, genre := range genres {
+		txt += "\t" + genre + ":\n" + p.Genres[genre].String()
+	}
+	return txt
+}
+
+// Load the FilePolicy section of the main configuration file.
+func (p *FilePolicy) Load(path string) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	section := struct {
+		FilePolicy *FilePolicy `yaml:"FilePolicy"`
+	}{}
+	err = yaml.Unmarshal(data, &section)
+	if err != nil {
+		panic(err)
+	}
+	*p = DefaultFilePolicy()
+	if section.FilePolicy != nil {
+		p.merge(*section.FilePolicy)
+	}
+	return
+}
+
+// merge the categories and genre rules of another policy over this one.
+// Extensions it lists leave the categories they were in, and categories it lists as empty are cleared.
+func (p *FilePolicy) merge(other FilePolicy) {
+	for _, extensions := range other.Default {
+		for _, e := range extensions {
+			p.Default.remove(e)
+		}
+	}
+	for category, extensions := range other.Default {
+		p.Default[category] = extensions
+	}
+	if len(other.Genres) != 0 && p.Genres == nil {
+		p.Genres = make(map[string]FileTypes)
+	}
+	for genre, types := range other.Genres {
+		p.Genres[genre] = types
+	}
+}
+
+// Classify returns the category of a file found in an album of a given genre.
+// Rules for the genre take precedence over those of its parent genres, which take precedence over the default ones.
+func (p *FilePolicy) Classify(genre, file string) string {
+	extension := strings.ToLower(filepath.Ext(file))
+	for ; genre != ""; genre = parentGenre(genre) {
+		if types, ok := p.Genres[genre]; ok {
+			if category, found := types.category(extension); found {
+				return category
+			}
+		}
+	}
+	if category, found := p.Default.category(extension); found {
+		return category
+	}
+	return Unknown
+}
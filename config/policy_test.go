@@ -0,0 +1,129 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testPolicy = FilePolicy{
+	Default: FileTypes{
+		AudioLossless: {".flac"},
+		AudioLossy:    {".mp3"},
+		Artwork:       {".jpg"},
+		RipMetadata:   {".log", ".txt"},
+		Junk:          {".DS_Store"},
+		Forbidden:     {".exe"},
+	},
+	Genres: map[string]FileTypes{
+		"Classical":       {RipMetadata: {".pdf"}, Junk: {".txt"}},
+		"Classical/Opera": {Artwork: {".pdf"}},
+	},
+}
+
+var testClassify = []struct {
+	genre    string
+	file     string
+	expected string
+}{
+	{"", "01 - track.flac", AudioLossless},
+	{"", "01 - track.MP3", AudioLossy},
+	{"", "cover.jpg", Artwork},
+	{"", "rip.log", RipMetadata},
+	{"", ".DS_Store", Junk},
+	{"", "setup.exe", Forbidden},
+	{"", "booklet.pdf", Unknown},
+	{"Classical", "booklet.pdf", RipMetadata},
+	{"Classical", "notes.txt", Junk},
+	{"Classical", "rip.log", RipMetadata},
+	// sub-genres use the rules of their parents
+	{"Classical/Opera", "libretto.pdf", Artwork},
+	{"Classical/Opera", "notes.txt", Junk},
+	{"Classical/Baroque", "booklet.pdf", RipMetadata},
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range testClassify {
+		if v := testPolicy.Classify(tc.genre, tc.file); v != tc.expected {
+			t.Errorf("Classify(%s, %s) returned %s, expected %s!", tc.genre, tc.file, v, tc.expected)
+		}
+	}
+}
+
+func TestFilePolicyLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "radis.yaml")
+	content := `FilePolicy:
+  Default:
+    junk: [.txt, .db]
+    forbidden: [.exe, .LOG]
+    audio-lossy: []
+  Genres:
+    Classical:
+      rip-metadata: [.pdf]
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var p FilePolicy
+	if err := p.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	expected := DefaultFilePolicy()
+	expected.Default[RipMetadata] = []string{".cue", ".m3u", ".m3u8", ".accurip", ".nfo", ".sfv"}
+	expected.Default[Junk] = []string{".txt", ".db"}
+	expected.Default[Forbidden] = []string{".exe", ".LOG"}
+	expected.Default[AudioLossy] = []string{}
+	expected.Genres = map[string]FileTypes{"Classical": {RipMetadata: {".pdf"}}}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("Load returned %v, expected %v", p, expected)
+	}
+	for file, expected := range map[string]string{
+		"notes.txt": Junk,
+		"rip.log":   Forbidden,
+		"01.mp3":    Unknown,
+		"cover.jpg": Artwork,
+		"rip.cue":   RipMetadata,
+	} {
+		if v := p.Classify("", file); v != expected {
+			t.Errorf("Classify(%s) returned %s, expected %s", file, v, expected)
+		}
+	}
+}
+
+func TestFilePolicyLoadGenresOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "radis.yaml")
+	content := `FilePolicy:
+  Genres:
+    Classical:
+      artwork: [.pdf]
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var p FilePolicy
+	if err := p.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Default, DefaultFilePolicy().Default) {
+		t.Errorf("Load returned default rules %v, expected the default policy", p.Default)
+	}
+	for file, expected := range map[string]string{"booklet.pdf": Artwork, "01.mp3": AudioLossy, "01.flac": AudioLossless} {
+		if v := p.Classify("Classical", file); v != expected {
+			t.Errorf("Classify(Classical, %s) returned %s, expected %s", file, v, expected)
+		}
+	}
+}
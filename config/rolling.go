@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Periods of rolling playlists.
+const (
+	Daily  = "daily"
+	Weekly = "weekly"
+	// Monthly and Yearly playlists contain the new albums of the current month or year.
+	Monthly = "monthly"
+	Yearly  = "yearly"
+	// Last playlists contain the last Count new albums.
+	Last = "last"
+)
+
+// Placeholders of rolling playlist filenames. Weekly playlists use the ISO year and week.
+const (
+	yearPlaceholder  = "{year}"
+	monthPlaceholder = "{month}"
+	dayPlaceholder   = "{day}"
+	weekPlaceholder  = "{week}"
+)
+
+// periodPlaceholders are what the filenames of rolling playlists must contain, so that each period has its own playlist.
+var periodPlaceholders = map[string][]string{
+	Daily:   {yearPlaceholder, monthPlaceholder, dayPlaceholder},
+	Weekly:  {yearPlaceholder, weekPlaceholder},
+	Monthly: {yearPlaceholder, monthPlaceholder},
+	Yearly:  {yearPlaceholder},
+}
+
+// RollingPlaylist collects the new albums found during syncs, in a new playlist for each period.
+type RollingPlaylist struct {
+	Period   string `yaml:"Period"`
+	Filename string `yaml:"Filename"`
+	// Count is the number of albums of a Last playlist.
+	Count int `yaml:"Count"`
+	// Keep is the number of periods to keep, including the current one; all if 0.
+	Keep int `yaml:"Keep"`
+	// Archive is where expired playlists are moved; they are deleted if empty.
+	Archive string `yaml:"Archive"`
+	// Order of the albums, in order of discovery if empty.
+	Order string `yaml:"Order"`
+}
+
+func (r *RollingPlaylist) String() string {
+	txt := r.Period + ": " + r.Filename
+	if r.Period == Last {
+		txt += fmt.Sprintf(", %d albums", r.Count)
+	}
+	if r.Keep != 0 {
+		txt += fmt.Sprintf(", keep %d", r.Keep)
+		if r.Archive != "" {
+			txt += ", archived in " + r.Archive
+		}
+	}
+	return txt + "\n"
+}
+
+// check the settings of a rolling playlist.
+func (r *RollingPlaylist) check() error {
+	if r.Filename == "" {
+		return errors.New("Rolling playlists must have a Filename.")
+	}
+	switch r.Period {
+	case Daily, Weekly, Monthly, Yearly:
+		for _, placeholder := range periodPlaceholders[r.Period] {
+			if !strings.Contains(r.Filename, placeholder) {
+				return errors.New("Rolling playlist " + r.Filename + " must contain " + strings.Join(periodPlaceholders[r.Period], ", "))
+			}
+		}
+	case Last:
+		if r.Count <= 0 {
+			return errors.New("Rolling playlist " + r.Filename + " must have a positive Count.")
+		}
+	default:
+		return errors.New("Unknown period for rolling playlist " + r.Filename + ": " + r.Period)
+	}
+	if r.Order != "" && !IsValidOrder(r.Order) {
+		return errors.New("Unknown order for rolling playlist " + r.Filename + ": " + r.Order)
+	}
+	return nil
+}
+
+// FilenameAt returns the filename of the playlist for the period containing a given time.
+func (r *RollingPlaylist) FilenameAt(t time.Time) string {
+	year, week := t.ISOWeek()
+	if r.Period != Weekly {
+		year = t.Year()
+	}
+	filename := strings.Replace(r.Filename, yearPlaceholder, fmt.Sprintf("%04d", year), -1)
+	filename = strings.Replace(filename, monthPlaceholder, fmt.Sprintf("%02d", t.Month()), -1)
+	filename = strings.Replace(filename, dayPlaceholder, fmt.Sprintf("%02d", t.Day()), -1)
+	return strings.Replace(filename, weekPlaceholder, fmt.Sprintf("%02d", week), -1)
+}
+
+// periodStart returns the beginning of the period containing a given time.
+func (r *RollingPlaylist) periodStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch r.Period {
+	case Weekly:
+		// ISO weeks start on monday
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Monthly:
+		return day.AddDate(0, 0, 1-day.Day())
+	case Yearly:
+		return day.AddDate(0, 0, 1-day.YearDay())
+	}
+	return day
+}
+
+// addPeriods moves a time by a number of periods.
+func (r *RollingPlaylist) addPeriods(t time.Time, n int) time.Time {
+	switch r.Period {
+	case Weekly:
+		return t.AddDate(0, 0, 7*n)
+	case Monthly:
+		return t.AddDate(0, n, 0)
+	case Yearly:
+		return t.AddDate(n, 0, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// filenamePattern matches the filenames generated from the template, capturing the placeholders.
+func (r *RollingPlaylist) filenamePattern() *regexp.Regexp {
+	pattern := regexp.QuoteMeta(r.Filename)
+	for placeholder, group := range map[string]string{
+		yearPlaceholder:  `(?P<year>\d{4})`,
+		monthPlaceholder: `(?P<month>\d{2})`,
+		dayPlaceholder:   `(?P<day>\d{2})`,
+		weekPlaceholder:  `(?P<week>\d{2})`,
+	} {
+		// only the first occurrence is captured
+		pattern = strings.Replace(pattern, regexp.QuoteMeta(placeholder), group, 1)
+		pattern = strings.Replace(pattern, regexp.QuoteMeta(placeholder), `\d+`, -1)
+	}
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// PeriodOf returns the beginning of the period of a playlist, from its filename.
+func (r *RollingPlaylist) PeriodOf(filename string, location *time.Location) (start time.Time, ok bool) {
+	if r.Period == Last {
+		return
+	}
+	pattern := r.filenamePattern()
+	matches := pattern.FindStringSubmatch(filename)
+	if matches == nil {
+		return
+	}
+	values := map[string]int{"year": 0, "month": 1, "day": 1, "week": 1}
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			values[name], _ = strconv.Atoi(matches[i])
+		}
+	}
+	if r.Period == Weekly {
+		// the monday of week 1 is in the week of january 4th
+		jan4 := time.Date(values["year"], time.January, 4, 0, 0, 0, 0, location)
+		return r.periodStart(jan4).AddDate(0, 0, 7*(values["week"]-1)), true
+	}
+	return r.periodStart(time.Date(values["year"], time.Month(values["month"]), values["day"], 0, 0, 0, 0, location)), true
+}
+
+// IsExpired checks if a playlist, by its filename, is older than the periods to keep.
+func (r *RollingPlaylist) IsExpired(filename string, now time.Time) bool {
+	if r.Keep == 0 {
+		return false
+	}
+	start, ok := r.PeriodOf(filename, now.Location())
+	if !ok {
+		return false
+	}
+	return start.Before(r.addPeriods(r.periodStart(now), 1-r.Keep))
+}
+
+// RollingPlaylists lists the playlists that collect new albums.
+type RollingPlaylists []RollingPlaylist
+
+// DefaultRollingPlaylists are used when radis.yaml does not define RollingPlaylists.
+func DefaultRollingPlaylists() RollingPlaylists {
+	return RollingPlaylists{
+		{Period: Daily, Filename: "{year}-{month}-{day}.m3u"},
+		{Period: Monthly, Filename: "{year}-{month}.m3u"},
+	}
+}
+
+func (rp *RollingPlaylists) String() (txt string) {
+	txt = "Rolling playlists:\n"
+	for _, r := range *rp {
+		txt += "\t" + r.String()
+	}
+	return
+}
+
+// Load the RollingPlaylists section of the main configuration file.
+func (rp *RollingPlaylists) Load(path string) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	section := struct {
+		RollingPlaylists *RollingPlaylists `yaml:"RollingPlaylists"`
+	}{}
+	err = yaml.Unmarshal(data, &section)
+	if err != nil {
+		panic(err)
+	}
+	if section.RollingPlaylists == nil {
+		*rp = DefaultRollingPlaylists()
+		return
+	}
+	*rp = *section.RollingPlaylists
+	for _, r := range *rp {
+		if err = r.check(); err != nil {
+			return
+		}
+	}
+	return
+}
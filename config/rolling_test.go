@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2016, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+var testRolling = []struct {
+	playlist         RollingPlaylist
+	expectedFilename string
+	candidate        string
+	expectedExpired  bool
+}{
+	{RollingPlaylist{Period: Daily, Filename: "{year}-{month}-{day}.m3u", Keep: 3}, "2016-01-02.m3u", "2015-12-31.m3u", false},
+	{RollingPlaylist{Period: Daily, Filename: "{year}-{month}-{day}.m3u", Keep: 3}, "2016-01-02.m3u", "2015-12-30.m3u", true},
+	{RollingPlaylist{Period: Daily, Filename: "{year}-{month}-{day}.m3u"}, "2016-01-02.m3u", "2015-12-30.m3u", false},
+	{RollingPlaylist{Period: Daily, Filename: "{year}-{month}-{day}.m3u", Keep: 3}, "2016-01-02.m3u", "2015-12.m3u", false},
+	// 2016-01-02 is in the last ISO week of 2015
+	{RollingPlaylist{Period: Weekly, Filename: "{year}-W{week}.m3u", Keep: 2}, "2015-W53.m3u", "2015-W52.m3u", false},
+	{RollingPlaylist{Period: Weekly, Filename: "{year}-W{week}.m3u", Keep: 2}, "2015-W53.m3u", "2015-W51.m3u", true},
+	{RollingPlaylist{Period: Monthly, Filename: "new {year}-{month}.m3u", Keep: 1}, "new 2016-01.m3u", "new 2015-12.m3u", true},
+	{RollingPlaylist{Period: Yearly, Filename: "{year}.m3u", Keep: 2}, "2016.m3u", "2015.m3u", false},
+	{RollingPlaylist{Period: Last, Filename: "last.m3u", Count: 10, Keep: 2}, "last.m3u", "last.m3u", false},
+}
+
+func TestRollingPlaylist(t *testing.T) {
+	for _, tr := range testRolling {
+		if err := tr.playlist.check(); err != nil {
+			t.Errorf("check(%s) returned %s", tr.playlist.String(), err.Error())
+		}
+		if v := tr.playlist.FilenameAt(testNow); v != tr.expectedFilename {
+			t.Errorf("FilenameAt(%s) returned %s, expected %s", tr.playlist.String(), v, tr.expectedFilename)
+		}
+		if v := tr.playlist.IsExpired(tr.candidate, testNow); v != tr.expectedExpired {
+			t.Errorf("IsExpired(%s, %s) returned %v, expected %v", tr.playlist.String(), tr.candidate, v, tr.expectedExpired)
+		}
+	}
+}
+
+func TestRollingPlaylistCheck(t *testing.T) {
+	invalid := []RollingPlaylist{
+		{Period: Daily},
+		{Period: Daily, Filename: "daily.m3u"},
+		{Period: Last, Filename: "last.m3u"},
+		{Period: "hourly", Filename: "{year}.m3u"},
+		// one playlist for the whole year would expire while being written
+		{Period: Daily, Filename: "{year}.m3u", Keep: 7},
+		{Period: Daily, Filename: "{year}-{month}.m3u"},
+		{Period: Weekly, Filename: "{year}-{month}.m3u"},
+		{Period: Monthly, Filename: "{year}-W{week}.m3u"},
+	}
+	for _, r := range invalid {
+		if err := r.check(); err == nil {
+			t.Errorf("check(%s) should have failed", r.String())
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeAtomically writes a file through a temporary file in the same directory, renamed once complete,
+// so that the file is never left half-written. Existing permissions are kept.
+func writeAtomically(path string, data []byte) (err error) {
+	mode := os.FileMode(0644)
+	if fileInfo, err := os.Stat(path); err == nil {
+		mode = fileInfo.Mode().Perm()
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// listEntry is where a list is found in the YAML tree.
+type listEntry struct {
+	key   *yaml.Node
+	value *yaml.Node
+	// mapping contains the key and value, and is an item of sequence if the list is nested in another one.
+	mapping  *yaml.Node
+	sequence *yaml.Node
+}
+
+// listFile is a YAML file mapping names to lists of strings, such as radis_genres.yaml or radis_aliases.yaml.
+// It is updated in place, so that comments are kept.
+type listFile struct {
+	lists map[string][]string
+	// nested is set if lists can contain other lists, as genres contain sub-genres.
+	nested bool
+	// less orders the names, if they must be sorted.
+	less func(a, b string) bool
+}
+
+// find indexes the lists of a mapping node by name, in order.
+func (lf *listFile) find(mapping, sequence *yaml.Node, prefix string, entries map[string][]listEntry, names *[]string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		name := key.Value
+		if lf.nested {
+			name = strings.Trim(prefix+key.Value, GenreSeparator)
+		}
+		if _, ok := entries[name]; !ok {
+			*names = append(*names, name)
+		}
+		entries[name] = append(entries[name], listEntry{key: key, value: value, mapping: mapping, sequence: sequence})
+		if !lf.nested {
+			continue
+		}
+		switch value.Kind {
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				if item.Kind == yaml.MappingNode {
+					lf.find(item, value, name+GenreSeparator, entries, names)
+				}
+			}
+		case yaml.MappingNode:
+			lf.find(value, nil, name+GenreSeparator, entries, names)
+		}
+	}
+}
+
+// hasNestedLists checks if a list, or one of the lists it contains, still exists.
+func (lf *listFile) hasNestedLists(name string) bool {
+	for n := range lf.lists {
+		if n == name || (lf.nested && strings.HasPrefix(n, name+GenreSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// setItems replaces the strings of a list, keeping the nodes of those that remain, with their comments.
+// Strings are sorted, and nested lists follow them.
+func setItems(value *yaml.Node, items []string) {
+	existing := make(map[string]*yaml.Node)
+	nested := []*yaml.Node{}
+	switch value.Kind {
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind == yaml.ScalarNode {
+				existing[item.Value] = item
+			} else {
+				nested = append(nested, item)
+			}
+		}
+	case yaml.MappingNode:
+		if len(items) == 0 {
+			return
+		}
+		// a map of nested lists becomes an item of the list
+		mapping := *value
+		mapping.HeadComment, mapping.LineComment, mapping.FootComment = "", "", ""
+		nested = append(nested, &mapping)
+	}
+	value.Kind, value.Tag, value.Value, value.Style = yaml.SequenceNode, "!!seq", "", 0
+
+	sorted := append([]string{}, items...)
+	sort.Strings(sorted)
+	value.Content = []*yaml.Node{}
+	for _, item := range sorted {
+		node, ok := existing[item]
+		if !ok {
+			node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item}
+		}
+		value.Content = append(value.Content, node)
+	}
+	value.Content = append(value.Content, nested...)
+}
+
+// removeEntry removes a list from its mapping, and the mapping from its sequence if it is then empty.
+func removeEntry(e listEntry) {
+	for i := 0; i+1 < len(e.mapping.Content); i += 2 {
+		if e.mapping.Content[i] == e.key {
+			e.mapping.Content = append(e.mapping.Content[:i], e.mapping.Content[i+2:]...)
+			break
+		}
+	}
+	if len(e.mapping.Content) != 0 || e.sequence == nil {
+		return
+	}
+	for i, item := range e.sequence.Content {
+		if item == e.mapping {
+			e.sequence.Content = append(e.sequence.Content[:i], e.sequence.Content[i+1:]...)
+			break
+		}
+	}
+}
+
+// sortMapping orders the names of a mapping, and of the mappings it contains.
+func (lf *listFile) sortMapping(mapping *yaml.Node) {
+	pairs := [][2]*yaml.Node{}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return lf.less(pairs[i][0].Value, pairs[j][0].Value) })
+	mapping.Content = []*yaml.Node{}
+	for _, p := range pairs {
+		mapping.Content = append(mapping.Content, p[0], p[1])
+		switch p[1].Kind {
+		case yaml.MappingNode:
+			lf.sortMapping(p[1])
+		case yaml.SequenceNode:
+			for _, item := range p[1].Content {
+				if item.Kind == yaml.MappingNode {
+					lf.sortMapping(item)
+				}
+			}
+		}
+	}
+}
+
+// firstLine returns the line where a name starts, with its comment.
+func firstLine(key *yaml.Node) int {
+	if key.HeadComment == "" {
+		return key.Line
+	}
+	return key.Line - strings.Count(key.HeadComment, "\n") - 1
+}
+
+// blankLinesBefore returns the names of the lists that follow a blank line, which yaml.v3 does not keep.
+func (lf *listFile) blankLinesBefore(data []byte, root *yaml.Node) map[string]bool {
+	lines := strings.Split(string(data), "\n")
+	entries := make(map[string][]listEntry)
+	names := []string{}
+	lf.find(root, nil, "", entries, &names)
+	blank := make(map[string]bool)
+	for _, name := range names {
+		if line := firstLine(entries[name][0].key); line >= 2 && strings.TrimSpace(lines[line-2]) == "" {
+			blank[name] = true
+		}
+	}
+	return blank
+}
+
+// restoreBlankLines adds blank lines back before the lists that followed one, except at the top of the file.
+func (lf *listFile) restoreBlankLines(out []byte, blank map[string]bool) ([]byte, error) {
+	if len(blank) == 0 {
+		return out, nil
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(out, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return out, nil
+	}
+	entries := make(map[string][]listEntry)
+	names := []string{}
+	lf.find(document.Content[0], nil, "", entries, &names)
+	before := make(map[int]bool)
+	for name := range blank {
+		if e, ok := entries[name]; ok {
+			before[firstLine(e[0].key)] = true
+		}
+	}
+	lines := strings.SplitAfter(string(out), "\n")
+	var buffer bytes.Buffer
+	for i, line := range lines {
+		if before[i+1] && i > 0 && strings.TrimSpace(lines[i-1]) != "" {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(line)
+	}
+	return buffer.Bytes(), nil
+}
+
+// update applies the lists to a YAML document, and returns it.
+func (lf *listFile) update(data []byte, keepOrder bool) (out []byte, err error) {
+	var document yaml.Node
+	if err = yaml.Unmarshal(data, &document); err != nil {
+		return
+	}
+	if document.Kind != yaml.DocumentNode {
+		document = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := document.Content[0]
+	blank := lf.blankLinesBefore(data, root)
+
+	entries := make(map[string][]listEntry)
+	names := []string{}
+	lf.find(root, nil, "", entries, &names)
+
+	// remove lists that are gone, nested lists first
+	sort.SliceStable(names, func(i, j int) bool {
+		return strings.Count(names[i], GenreSeparator) > strings.Count(names[j], GenreSeparator)
+	})
+	for _, name := range names {
+		items, exists := lf.lists[name]
+		for i, e := range entries[name] {
+			switch {
+			case exists && i == 0:
+				setItems(e.value, items)
+			case lf.hasNestedLists(name):
+				// only its nested lists remain, or it is defined twice
+				if setItems(e.value, nil); len(e.value.Content) == 0 {
+					removeEntry(e)
+				}
+			default:
+				removeEntry(e)
+			}
+		}
+	}
+	// add new lists at the end
+	added := []string{}
+	for name := range lf.lists {
+		if _, ok := entries[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return lf.less(added[i], added[j]) })
+	for _, name := range added {
+		value := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setItems(value, lf.lists[name])
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, value)
+	}
+	if !keepOrder {
+		// a comment at the top of the file is attached to the first name, unless a blank line follows it
+		if len(root.Content) != 0 && document.HeadComment == "" {
+			document.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+		}
+		lf.sortMapping(root)
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&document); err != nil {
+		return
+	}
+	if err = encoder.Close(); err != nil {
+		return
+	}
+	return lf.restoreBlankLines(buffer.Bytes(), blank)
+}
+
+// write updates a file with the lists, atomically.
+func (lf *listFile) write(path string, keepOrder bool) (err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	out, err := lf.update(data, keepOrder)
+	if err != nil {
+		return
+	}
+	return writeAtomically(path, out)
+}
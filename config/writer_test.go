@@ -0,0 +1,226 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGenresFile = `# my genres
+Rock:
+  - Radiohead # the best
+  - Blur
+# heavier
+Metal:
+  - Metallica
+  - Doom:
+      # slow
+      - Sleep
+  - Sludge:
+      - Melvins
+Jazz:
+  # cool
+  - Miles
+`
+
+func TestGenresWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "radis_genres.yaml")
+	if err := ioutil.WriteFile(path, []byte(testGenresFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var g Genres
+	if err := g.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddArtist("Rock", "Air"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RemoveArtist("Metal/Sludge", "Melvins"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddArtist("Blues", "Muddy"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Write(path, true); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# my genres
+Rock:
+  - Air
+  - Blur
+  - Radiohead # the best
+# heavier
+Metal:
+  - Metallica
+  - Doom:
+      # slow
+      - Sleep
+Jazz:
+  # cool
+  - Miles
+Blues:
+  - Muddy
+`
+	written, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != expected {
+		t.Errorf("Write kept the order as:\n%s\nexpected:\n%s", written, expected)
+	}
+
+	if err := g.Write(path, false); err != nil {
+		t.Fatal(err)
+	}
+	expected = `# my genres
+
+Blues:
+  - Muddy
+Jazz:
+  # cool
+  - Miles
+# heavier
+Metal:
+  - Metallica
+  - Doom:
+      # slow
+      - Sleep
+Rock:
+  - Air
+  - Blur
+  - Radiohead # the best
+`
+	if written, err = ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != expected {
+		t.Errorf("Write sorted genres as:\n%s\nexpected:\n%s", written, expected)
+	}
+	// sorting again changes nothing
+	if err := g.Write(path, false); err != nil {
+		t.Fatal(err)
+	}
+	if written, err = ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != expected {
+		t.Errorf("Write sorted genres again as:\n%s\nexpected:\n%s", written, expected)
+	}
+
+	// what is written loads the same
+	var loaded Genres
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, g) {
+		t.Errorf("Load after Write returned %v, expected %v", loaded, g)
+	}
+	// no temporary files are left behind
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Write left %d files in %s", len(files), dir)
+	}
+}
+
+func TestBlankLinesWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "radis_genres.yaml")
+	content := `# my genres
+Rock:
+  - ACDC
+
+# heavier
+Metal:
+  - Doom:
+      - Sleep
+
+  - Sludge:
+      - Melvins
+
+Jazz:
+  - Miles
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var g Genres
+	if err := g.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddArtist("Jazz", "Monk"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Write(path, true); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(content, "  - Miles\n", "  - Miles\n  - Monk\n", 1)
+	written, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != expected {
+		t.Errorf("Write kept the order as:\n%s\nexpected:\n%s", written, expected)
+	}
+
+	if err := g.Write(path, false); err != nil {
+		t.Fatal(err)
+	}
+	expected = `# my genres
+
+Jazz:
+  - Miles
+  - Monk
+
+# heavier
+Metal:
+  - Doom:
+      - Sleep
+
+  - Sludge:
+      - Melvins
+Rock:
+  - ACDC
+`
+	if written, err = ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != expected {
+		t.Errorf("Write sorted genres as:\n%s\nexpected:\n%s", written, expected)
+	}
+}
+
+func TestAliasesWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "radis_aliases.yaml")
+
+	// a new file is created
+	a := Aliases{Artist{MainAlias: "MF DOOM", Aliases: []string{"Viktor Vaughn", "JJ DOOM"}}}
+	if err := a.Write(path, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := "MF DOOM:\n  - JJ DOOM\n  - Viktor Vaughn\n"
+	written, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != expected {
+		t.Errorf("Write returned:\n%s\nexpected:\n%s", written, expected)
+	}
+}
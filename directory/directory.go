@@ -59,15 +59,16 @@ func GetFiles(path string) (contents []string, err error) {
 	return fileList, err
 }
 
-// GetPlaylists returns a list of .m3u files.
+// GetPlaylists returns a list of .m3u, .m3u8, .xspf and .pls files.
 func GetPlaylists(playlistRoot string) (contents []string, err error) {
 	fileList, err := GetFiles(playlistRoot)
 	if err != nil {
 		return []string{}, err
 	}
-	// check for m3u files
+	// check for playlist files
 	for _, file := range fileList {
-		if filepath.Ext(file) == ".m3u" {
+		switch filepath.Ext(file) {
+		case ".m3u", ".m3u8", ".xspf", ".pls":
 			// accepted extensions
 			contents = append(contents, file)
 		}
@@ -0,0 +1,205 @@
+// Package mpd is a small client for the MPD protocol.
+package mpd
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pollInterval is how often the status is checked while waiting for a database update.
+const pollInterval = 100 * time.Millisecond
+
+// Pair is a line of an MPD response.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Client is a connection to an MPD server.
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	Version string
+}
+
+// Dial connects to MPD over "tcp" or "unix", and sends the password if there is one.
+func Dial(network, address, password string) (c *Client, err error) {
+	conn, err := net.DialTimeout(network, address, 10*time.Second)
+	if err != nil {
+		return
+	}
+	c = &Client{conn: conn, reader: bufio.NewReader(conn)}
+	greeting, err := c.reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "OK MPD ") {
+		conn.Close()
+		return nil, errors.New("Not an MPD server: " + strings.TrimSpace(greeting))
+	}
+	c.Version = strings.TrimSpace(strings.TrimPrefix(greeting, "OK MPD "))
+	if password != "" {
+		if _, err = c.Command("password", password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return
+}
+
+// Close the connection.
+func (c *Client) Close() error {
+	c.conn.Write([]byte("close\n"))
+	return c.conn.Close()
+}
+
+// quote an argument of a command.
+func quote(argument string) string {
+	argument = strings.Replace(argument, `\`, `\\`, -1)
+	argument = strings.Replace(argument, `"`, `\"`, -1)
+	return `"` + argument + `"`
+}
+
+// Command sends a command and returns the lines of the response.
+func (c *Client) Command(command string, arguments ...string) (response []Pair, err error) {
+	line := command
+	for _, argument := range arguments {
+		line += " " + quote(argument)
+	}
+	if _, err = c.conn.Write([]byte(line + "\n")); err != nil {
+		return
+	}
+	for {
+		l, err := c.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		l = strings.TrimSuffix(l, "\n")
+		switch {
+		case l == "OK":
+			return response, nil
+		case strings.HasPrefix(l, "ACK "):
+			return nil, errors.New("MPD error: " + strings.TrimPrefix(l, "ACK "))
+		}
+		parts := strings.SplitN(l, ": ", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("Unexpected MPD response: " + l)
+		}
+		response = append(response, Pair{Key: parts[0], Value: parts[1]})
+	}
+}
+
+// values returns the values of a key in a response.
+func values(response []Pair, key string) (values []string) {
+	for _, p := range response {
+		if p.Key == key {
+			values = append(values, p.Value)
+		}
+	}
+	return
+}
+
+// Status returns the current status of MPD.
+func (c *Client) Status() (status map[string]string, err error) {
+	response, err := c.Command("status")
+	if err != nil {
+		return
+	}
+	status = make(map[string]string)
+	for _, p := range response {
+		status[p.Key] = p.Value
+	}
+	return
+}
+
+// Update starts updating the database for a directory relative to the music directory,
+// or for everything if it is empty. It returns the job id.
+func (c *Client) Update(directory string) (job int, err error) {
+	var response []Pair
+	if directory == "" {
+		response, err = c.Command("update")
+	} else {
+		response, err = c.Command("update", directory)
+	}
+	if err != nil {
+		return
+	}
+	jobs := values(response, "updating_db")
+	if len(jobs) == 0 {
+		return 0, errors.New("MPD did not start updating its database.")
+	}
+	return strconv.Atoi(jobs[0])
+}
+
+// WaitForUpdate waits until MPD has finished updating its database, up to job, or completely if job is 0.
+func (c *Client) WaitForUpdate(job int, timeout time.Duration) (err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+		current, isUpdating := status["updating_db"]
+		if !isUpdating {
+			return nil
+		}
+		// jobs are numbered in order, a later job means ours is done
+		if id, err := strconv.Atoi(current); err == nil && job != 0 && id > job {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("Timeout while waiting for MPD to update its database.")
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Add appends a file or directory of the database to the queue.
+func (c *Client) Add(uri string) (err error) {
+	_, err = c.Command("add", uri)
+	return
+}
+
+// Stickers returns the values of a song sticker, by file, for the whole database.
+func (c *Client) Stickers(name string) (stickers map[string]string, err error) {
+	response, err := c.Command("sticker", "find", "song", "", name)
+	if err != nil {
+		return
+	}
+	stickers = make(map[string]string)
+	file := ""
+	for _, p := range response {
+		switch p.Key {
+		case "file":
+			file = p.Value
+		case "sticker":
+			if value := strings.TrimPrefix(p.Value, name+"="); value != p.Value {
+				stickers[file] = value
+			}
+		}
+	}
+	return
+}
+
+// ListPlaylists returns the names of the stored playlists.
+func (c *Client) ListPlaylists() (playlists []string, err error) {
+	response, err := c.Command("listplaylists")
+	if err != nil {
+		return
+	}
+	return values(response, "playlist"), nil
+}
+
+// ListPlaylist returns the files of a stored playlist, as MPD reads them.
+func (c *Client) ListPlaylist(name string) (files []string, err error) {
+	response, err := c.Command("listplaylist", name)
+	if err != nil {
+		return
+	}
+	return values(response, "file"), nil
+}
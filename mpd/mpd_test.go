@@ -0,0 +1,149 @@
+package mpd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer answers MPD commands with canned responses, and records the commands it receives.
+type fakeServer struct {
+	listener  net.Listener
+	responses map[string][]string
+	received  chan string
+}
+
+func newFakeServer(t *testing.T, network, address string, responses map[string][]string) *fakeServer {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, responses: responses, received: make(chan string, 100)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte("OK MPD 0.21.0\n"))
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		s.received <- line
+		if line == "close" {
+			return
+		}
+		response, ok := s.responses[line]
+		if !ok {
+			conn.Write([]byte("ACK [5@0] {} unknown command \"" + line + "\"\n"))
+			continue
+		}
+		// the first response is used once if there are several
+		if len(response) > 1 && strings.HasPrefix(response[0], "+") {
+			s.responses[line] = response[1:]
+			response = []string{strings.TrimPrefix(response[0], "+")}
+		}
+		for _, l := range response {
+			conn.Write([]byte(l + "\n"))
+		}
+		conn.Write([]byte("OK\n"))
+	}
+}
+
+func (s *fakeServer) commands() (commands []string) {
+	for {
+		select {
+		case c := <-s.received:
+			commands = append(commands, c)
+		default:
+			return
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	s := newFakeServer(t, "tcp", "127.0.0.1:0", map[string][]string{
+		`password "se\"cret"`: {},
+		`update "Jazz/Miles"`: {"updating_db: 3"},
+		"status":              {"+updating_db: 3", "state: stop"},
+		"listplaylists":       {"playlist: a", "Last-Modified: 2016-01-01T00:00:00Z", "playlist: b"},
+		`listplaylist "a"`:    {"file: Jazz/Miles/Miles (1959) Blue/01.flac"},
+		`sticker "find" "song" "" "playcount"`: {
+			"file: a/01.flac", "sticker: playcount=3",
+			"file: a/02.flac", "sticker: playcount=1",
+		},
+	})
+	defer s.listener.Close()
+
+	c, err := Dial("tcp", s.listener.Addr().String(), `se"cret`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != "0.21.0" {
+		t.Errorf("Dial returned version %s", c.Version)
+	}
+	job, err := c.Update("Jazz/Miles")
+	if err != nil || job != 3 {
+		t.Errorf("Update returned %d, %v", job, err)
+	}
+	if err := c.WaitForUpdate(job, time.Second); err != nil {
+		t.Errorf("WaitForUpdate returned %s", err.Error())
+	}
+	playlists, err := c.ListPlaylists()
+	if err != nil || !reflect.DeepEqual(playlists, []string{"a", "b"}) {
+		t.Errorf("ListPlaylists returned %v, %v", playlists, err)
+	}
+	files, err := c.ListPlaylist("a")
+	if err != nil || len(files) != 1 {
+		t.Errorf("ListPlaylist returned %v, %v", files, err)
+	}
+	if _, err := c.ListPlaylist("missing"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("ListPlaylist should have returned an MPD error, got %v", err)
+	}
+	stickers, err := c.Stickers("playcount")
+	if expected := map[string]string{"a/01.flac": "3", "a/02.flac": "1"}; err != nil || !reflect.DeepEqual(stickers, expected) {
+		t.Errorf("Stickers returned %v, %v, expected %v", stickers, err, expected)
+	}
+	c.Close()
+
+	expected := []string{`password "se\"cret"`, `update "Jazz/Miles"`, "status", "status", "listplaylists", `listplaylist "a"`, `listplaylist "missing"`, `sticker "find" "song" "" "playcount"`}
+	if commands := s.commands(); !reflect.DeepEqual(commands[:len(expected)], expected) {
+		t.Errorf("server received %v, expected %v", commands, expected)
+	}
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	s := newFakeServer(t, "unix", socket, map[string][]string{"status": {"state: play"}})
+	defer s.listener.Close()
+
+	c, err := Dial("unix", socket, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	status, err := c.Status()
+	if err != nil || status["state"] != "play" {
+		t.Errorf("Status returned %v, %v", status, err)
+	}
+}
@@ -25,6 +25,7 @@ type Album struct {
 	NewPath   string // absolute
 	artist    string
 	mainAlias string
+	genre     string
 	year      string
 	title     string
 	IsMP3     bool
@@ -86,7 +87,7 @@ func (a *Album) FindNewPath(c config.Config) (hasGenre bool, err error) {
 			break
 		}
 	}
-	// find which genre the artist or main alias belongs to
+	// find which genre the artist or main alias belongs to, the deepest sub-genre if there are several
 	hasGenre = false
 	directoryName := filepath.Base(a.Path)
 	for _, genre := range c.Genres {
@@ -97,13 +98,14 @@ func (a *Album) FindNewPath(c config.Config) (hasGenre bool, err error) {
 			found = genre.HasArtist(a.mainAlias)
 		}
 		// if artist is known, it belongs to genre.Name
-		if found {
-			a.NewPath = filepath.Join(a.Root, genre.Name, a.mainAlias, directoryName)
+		if found && (!hasGenre || genre.Depth() > strings.Count(a.genre, config.GenreSeparator)) {
+			a.NewPath = filepath.Join(a.Root, filepath.FromSlash(genre.Name), a.mainAlias, directoryName)
+			a.genre = genre.Name
 			hasGenre = true
-			break
 		}
 	}
 	if !hasGenre {
+		a.genre = ""
 		a.NewPath = filepath.Join(a.Root, c.Paths.UnsortedSubdir, a.mainAlias, directoryName)
 	}
 	return
@@ -140,9 +142,40 @@ func (a *Album) MoveToNewPath(doNothing bool) (hasMoved bool, err error) {
 	return
 }
 
+// mp3FlaggedPath returns an album path with or without the [MP3] suffix.
+func mp3FlaggedPath(path string, isMP3 bool) string {
+	base := strings.TrimRight(strings.TrimSuffix(filepath.Base(path), "[MP3]"), " ")
+	if isMP3 {
+		base += " [MP3]"
+	}
+	return filepath.Join(filepath.Dir(path), base)
+}
+
+// SetMP3Flag renames an album directory to add or remove the [MP3] suffix.
+// It moves the album with MoveToNewPath, as sync does.
+func (a *Album) SetMP3Flag(isMP3 bool, doNothing bool) (hasMoved bool, err error) {
+	a.NewPath = mp3FlaggedPath(a.Path, isMP3)
+	hasMoved, err = a.MoveToNewPath(doNothing)
+	if hasMoved && !doNothing {
+		a.Path = a.NewPath
+		a.IsMP3 = isMP3
+	}
+	return
+}
+
 // GetMusicFiles returns flac or mp3 files of the album.
+// Files split by a CUE sheet are replaced by the virtual tracks MPD uses.
 func (a *Album) GetMusicFiles() (contents []string, err error) {
-	fileList, err := directory.GetFiles(a.NewPath)
+	files, err := getMusicFiles(a.NewPath)
+	if err != nil {
+		return
+	}
+	return expandCueSheets(a.NewPath, files)
+}
+
+// getMusicFiles returns flac or mp3 files found in a directory.
+func getMusicFiles(path string) (contents []string, err error) {
+	fileList, err := directory.GetFiles(path)
 	if err != nil {
 		return []string{}, err
 	}
@@ -151,33 +184,43 @@ func (a *Album) GetMusicFiles() (contents []string, err error) {
 		switch filepath.Ext(file) {
 		case ".flac", ".mp3":
 			// accepted extensions
-			contents = append(contents, filepath.Join(a.NewPath, file))
+			contents = append(contents, filepath.Join(path, file))
 		}
 	}
 	sort.Strings(contents)
 	return
 }
 
-// HasNonFlacFiles returns true if an album contains files other than flac songs and cover pictures.
-func (a *Album) HasNonFlacFiles() (bool, error) {
+// FileReport lists the files of an album directory by category.
+type FileReport map[string][]string
+
+// CheckFiles classifies the files of an album according to a FilePolicy.
+// FindNewPath must have been called first for the policy of the album's genre to apply.
+func (a *Album) CheckFiles(p config.FilePolicy) (report FileReport, err error) {
 	fileList, err := directory.GetFiles(a.Path)
 	if err != nil {
-		return false, err
+		return
 	}
-	// check for suspicious files
-	hasNonFlac := false
+	sort.Strings(fileList)
+	report = make(FileReport)
 	for _, file := range fileList {
-		switch filepath.Ext(file) {
-		case ".flac", ".jpg", ".jpeg", ".png":
-			// accepted extensions
-		case ".mp3", ".wma", ".m4a":
-			hasNonFlac = true
-			break
-		default:
-			fmt.Println("Found suspicious file ", file, " in ", a.Path)
-			hasNonFlac = true
-			break
+		if info, err := os.Stat(filepath.Join(a.Path, file)); err == nil && info.IsDir() {
+			continue
 		}
+		category := p.Classify(a.genre, file)
+		report[category] = append(report[category], file)
+	}
+	return
+}
+
+// HasNonFlacFiles returns true if an album contains lossy music files, or files the default policy does not know.
+func (a *Album) HasNonFlacFiles() (bool, error) {
+	report, err := a.CheckFiles(config.DefaultFilePolicy())
+	if err != nil {
+		return false, err
+	}
+	for _, file := range report[config.Unknown] {
+		fmt.Println("Found suspicious file ", file, " in ", a.Path)
 	}
-	return hasNonFlac, err
+	return len(report[config.AudioLossy]) != 0 || len(report[config.Unknown]) != 0, nil
 }
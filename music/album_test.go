@@ -133,6 +133,24 @@ func cleanTestFiles(c config.Config) {
 	}
 }
 
+// createTestAlbums creates albums with one empty track, relative to the root, and finds where they belong.
+func createTestAlbums(t *testing.T, c config.Config, paths ...string) (albums []Album) {
+	for _, path := range paths {
+		a := Album{Root: c.Paths.Root, Path: filepath.Join(c.Paths.Root, path)}
+		if err := os.MkdirAll(a.Path, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(a.Path, "01.flac"), []byte{}, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.FindNewPath(c); err != nil {
+			t.Fatal(err)
+		}
+		albums = append(albums, a)
+	}
+	return
+}
+
 // TestMain runs all tests, after creating temporary test files.
 func TestMain(m *testing.M) {
 	createTestFiles(c)
@@ -222,4 +240,24 @@ func TestHasNonFlacFiles(t *testing.T) {
 	}
 }
 
+var mp3FlaggedPaths = []struct {
+	path     string
+	isMP3    bool
+	expected string
+}{
+	{"/music/a/a (2000) title", true, "/music/a/a (2000) title [MP3]"},
+	{"/music/a/a (2000) title", false, "/music/a/a (2000) title"},
+	{"/music/a/a (2000) title [MP3]", false, "/music/a/a (2000) title"},
+	{"/music/a/a (2000) title[MP3]", false, "/music/a/a (2000) title"},
+	{"/music/a/a (2000) title[MP3]", true, "/music/a/a (2000) title [MP3]"},
+}
+
+func TestMP3FlaggedPath(t *testing.T) {
+	for _, tp := range mp3FlaggedPaths {
+		if v := mp3FlaggedPath(tp.path, tp.isMP3); v != tp.expected {
+			t.Errorf("mp3FlaggedPath(%s, %v) returned %s, expected %s", tp.path, tp.isMP3, v, tp.expected)
+		}
+	}
+}
+
 // TODO MoveToNewPath, GetMusicFiles
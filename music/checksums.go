@@ -0,0 +1,83 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// checksumIndexFile is kept next to the playlists, with the checksums of their tracks.
+const checksumIndexFile = ".radis_checksums.yaml"
+
+// fileSum identifies the contents of a music file, so that it can be found after it moved.
+type fileSum struct {
+	Size     int64     `yaml:"size"`
+	Modified time.Time `yaml:"modified"`
+	Checksum string    `yaml:"sha1"`
+}
+
+// checksumIndex maps music files, relative to the collection root, to their checksums.
+type checksumIndex map[string]fileSum
+
+// loadChecksumIndex reads the checksums kept in a playlist directory; a missing file gives an empty index.
+func loadChecksumIndex(dir string) (index checksumIndex, err error) {
+	index = make(checksumIndex)
+	data, err := ioutil.ReadFile(filepath.Join(dir, checksumIndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return
+	}
+	err = yaml.Unmarshal(data, &index)
+	return
+}
+
+// save the checksums in a playlist directory.
+func (ci checksumIndex) save(dir string) (err error) {
+	data, err := yaml.Marshal(ci)
+	if err != nil {
+		return
+	}
+	return ioutil.WriteFile(filepath.Join(dir, checksumIndexFile), data, 0600)
+}
+
+// add the checksums of files, by relative path, unless they are known and unchanged.
+// Files that moved away keep their checksums, so that they can be found again.
+func (ci checksumIndex) add(files map[string]string) (changed bool, err error) {
+	for relativePath, file := range files {
+		if isVirtualTrack(file) {
+			continue
+		}
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			return changed, err
+		}
+		if sum, ok := ci[relativePath]; ok && sum.Size == fileInfo.Size() && sum.Modified.Equal(fileInfo.ModTime()) {
+			continue
+		}
+		checksum, err := fileChecksum(file)
+		if err != nil {
+			return changed, err
+		}
+		ci[relativePath] = fileSum{Size: fileInfo.Size(), Modified: fileInfo.ModTime(), Checksum: checksum}
+		changed = true
+	}
+	return
+}
+
+// recordChecksums keeps the checksums of the tracks of a playlist in its directory.
+func recordChecksums(dir string, files map[string]string) (err error) {
+	index, err := loadChecksumIndex(dir)
+	if err != nil {
+		return
+	}
+	changed, err := index.add(files)
+	if err != nil || !changed {
+		return
+	}
+	return index.save(dir)
+}
@@ -0,0 +1,240 @@
+package music
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/barsanuphe/radis/directory"
+)
+
+// cueFramesPerSecond is the number of CD frames per second, used in INDEX positions.
+const cueFramesPerSecond = 75
+
+// CueTrack is a track described by a CUE sheet.
+type CueTrack struct {
+	Number    int
+	Title     string
+	Performer string
+	File      string
+	Start     time.Duration
+}
+
+// CueSheet describes how one or more audio files are split into tracks.
+type CueSheet struct {
+	Path      string
+	Performer string
+	Title     string
+	Files     []string
+	Tracks    []CueTrack
+}
+
+// String gives a representation of a CueSheet.
+func (cs *CueSheet) String() string {
+	return fmt.Sprintf("%s: %d files, %d tracks", filepath.Base(cs.Path), len(cs.Files), len(cs.Tracks))
+}
+
+// IsImage indicates if the CUE sheet describes a single file containing several tracks.
+func (cs *CueSheet) IsImage() bool {
+	return len(cs.Files) == 1 && len(cs.Tracks) > 1
+}
+
+// MissingFiles returns the files referenced by the CUE sheet that do not exist.
+func (cs *CueSheet) MissingFiles() (missing []string) {
+	for _, file := range cs.Files {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(cs.Path), file)); err != nil {
+			missing = append(missing, file)
+		}
+	}
+	return
+}
+
+// VirtualTracks returns the paths MPD uses for the tracks of a CUE sheet: album.cue/track0001, etc.
+func (cs *CueSheet) VirtualTracks() (tracks []string) {
+	for _, t := range cs.Tracks {
+		tracks = append(tracks, filepath.Join(cs.Path, fmt.Sprintf("track%04d", t.Number)))
+	}
+	return
+}
+
+// cueArgument returns the argument of a CUE command, without quotes.
+func cueArgument(line string) string {
+	if strings.HasPrefix(line, "\"") {
+		if end := strings.Index(line[1:], "\""); end != -1 {
+			return line[1 : end+1]
+		}
+	}
+	return line
+}
+
+// parseCueTime converts an INDEX position (mm:ss:ff) to a duration.
+func parseCueTime(position string) (duration time.Duration, err error) {
+	parts := strings.Split(position, ":")
+	if len(parts) != 3 {
+		return 0, errors.New("Invalid CUE position " + position)
+	}
+	values := make([]int, 3)
+	for i, part := range parts {
+		if values[i], err = strconv.Atoi(part); err != nil {
+			return 0, errors.New("Invalid CUE position " + position)
+		}
+	}
+	duration = time.Duration(values[0])*time.Minute + time.Duration(values[1])*time.Second +
+		time.Duration(values[2])*time.Second/cueFramesPerSecond
+	return
+}
+
+// ParseCueSheet reads a CUE sheet.
+func ParseCueSheet(path string) (cs CueSheet, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	cs, err = parseCueSheet(string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})))
+	cs.Path = path
+	return
+}
+
+// parseCueSheet extracts files and tracks from the text of a CUE sheet.
+func parseCueSheet(text string) (cs CueSheet, err error) {
+	currentFile := ""
+	var currentTrack *CueTrack
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimSpace(line)[len(fields[0]):])
+		argument := cueArgument(rest)
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			// the file type comes after the file name
+			if !strings.HasPrefix(rest, "\"") && len(fields) > 2 {
+				argument = strings.Join(fields[1:len(fields)-1], " ")
+			}
+			currentFile = argument
+			cs.Files = append(cs.Files, currentFile)
+		case "TRACK":
+			if len(fields) < 2 {
+				return cs, errors.New("Invalid TRACK line: " + line)
+			}
+			number, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return cs, errors.New("Invalid TRACK line: " + line)
+			}
+			if currentFile == "" {
+				return cs, errors.New("TRACK before FILE in CUE sheet")
+			}
+			cs.Tracks = append(cs.Tracks, CueTrack{Number: number, File: currentFile, Performer: cs.Performer})
+			currentTrack = &cs.Tracks[len(cs.Tracks)-1]
+		case "TITLE":
+			if currentTrack == nil {
+				cs.Title = argument
+			} else {
+				currentTrack.Title = argument
+			}
+		case "PERFORMER":
+			if currentTrack == nil {
+				cs.Performer = argument
+			} else {
+				currentTrack.Performer = argument
+			}
+		case "INDEX":
+			if currentTrack != nil && len(fields) == 3 && fields[1] == "01" {
+				if currentTrack.Start, err = parseCueTime(fields[2]); err != nil {
+					return
+				}
+			}
+		}
+	}
+	if len(cs.Tracks) == 0 {
+		err = errors.New("No tracks found in CUE sheet")
+	}
+	return
+}
+
+// getCueSheets returns the valid CUE sheets found in a directory.
+func getCueSheets(path string) (sheets []CueSheet, err error) {
+	fileList, err := directory.GetFiles(path)
+	if err != nil {
+		return
+	}
+	sort.Strings(fileList)
+	for _, file := range fileList {
+		if strings.ToLower(filepath.Ext(file)) != ".cue" {
+			continue
+		}
+		cs, err := ParseCueSheet(filepath.Join(path, file))
+		if err != nil {
+			// not a usable CUE sheet
+			continue
+		}
+		sheets = append(sheets, cs)
+	}
+	return
+}
+
+// GetCueSheets returns the CUE sheets of the album.
+func (a *Album) GetCueSheets() (sheets []CueSheet, err error) {
+	return getCueSheets(a.Path)
+}
+
+// expandCueSheets replaces the images described by CUE sheets in a list of files by their virtual tracks.
+func expandCueSheets(path string, files []string) (contents []string, err error) {
+	sheets, err := getCueSheets(path)
+	if err != nil {
+		return
+	}
+	images := make(map[string][]string)
+	for _, cs := range sheets {
+		if cs.IsImage() && len(cs.MissingFiles()) == 0 {
+			images[filepath.Join(path, cs.Files[0])] = cs.VirtualTracks()
+		}
+	}
+	for _, file := range files {
+		if tracks, ok := images[file]; ok {
+			contents = append(contents, tracks...)
+		} else {
+			contents = append(contents, file)
+		}
+	}
+	return
+}
+
+// isVirtualTrack checks if a path is a track of a CUE sheet, as MPD names them.
+func isVirtualTrack(path string) bool {
+	return strings.ToLower(filepath.Ext(filepath.Dir(path))) == ".cue"
+}
+
+// readVirtualTrackInfo returns the information about a track of a CUE sheet.
+func readVirtualTrackInfo(path string) (info TrackInfo, err error) {
+	info.Path = path
+	cs, err := ParseCueSheet(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "track"))
+	if err != nil {
+		return info, errors.New("Invalid CUE sheet track " + path)
+	}
+	for i, t := range cs.Tracks {
+		if t.Number != number {
+			continue
+		}
+		info.Artist, info.Title, info.Album = t.Performer, t.Title, cs.Title
+		if i+1 < len(cs.Tracks) && cs.Tracks[i+1].File == t.File {
+			info.Duration = cs.Tracks[i+1].Start - t.Start
+		} else if image, err := ReadTrackInfo(filepath.Join(filepath.Dir(cs.Path), t.File)); err == nil {
+			info.Duration = image.Duration - t.Start
+		}
+		return
+	}
+	return info, errors.New("Could not find track " + path)
+}
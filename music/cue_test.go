@@ -0,0 +1,81 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testCueSheet = `REM GENRE Jazz
+PERFORMER "Artist"
+TITLE "Album"
+FILE "Artist - Album.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    PERFORMER "Guest"
+    INDEX 00 04:10:00
+    INDEX 01 04:12:37
+`
+
+func TestParseCueSheet(t *testing.T) {
+	cs, err := parseCueSheet(testCueSheet)
+	if err != nil {
+		t.Fatalf("parseCueSheet returned an error: %s", err.Error())
+	}
+	expected := CueSheet{
+		Performer: "Artist",
+		Title:     "Album",
+		Files:     []string{"Artist - Album.flac"},
+		Tracks: []CueTrack{
+			{Number: 1, Title: "One", Performer: "Artist", File: "Artist - Album.flac"},
+			{Number: 2, Title: "Two", Performer: "Guest", File: "Artist - Album.flac", Start: 4*time.Minute + 12*time.Second + 37*time.Second/75},
+		},
+	}
+	if !reflect.DeepEqual(cs, expected) {
+		t.Errorf("parseCueSheet returned %+v, expected %+v", cs, expected)
+	}
+	if !cs.IsImage() {
+		t.Errorf("IsImage returned false, expected true")
+	}
+	if _, err := parseCueSheet("REM nothing"); err == nil {
+		t.Errorf("parseCueSheet should have returned an error without tracks")
+	}
+}
+
+func TestExpandCueSheets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_cue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cuePath := filepath.Join(dir, "Artist - Album.cue")
+	image := filepath.Join(dir, "Artist - Album.flac")
+	other := filepath.Join(dir, "bonus.flac")
+	if err := ioutil.WriteFile(cuePath, []byte(testCueSheet), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{image, other} {
+		if err := ioutil.WriteFile(file, []byte{}, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := getMusicFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := expandCueSheets(dir, files)
+	if err != nil {
+		t.Errorf("expandCueSheets returned an error: %s", err.Error())
+	}
+	expected := []string{filepath.Join(cuePath, "track0001"), filepath.Join(cuePath, "track0002"), other}
+	if !reflect.DeepEqual(contents, expected) {
+		t.Errorf("expandCueSheets returned %v, expected %v", contents, expected)
+	}
+}
@@ -0,0 +1,239 @@
+package music
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// durationTolerance is the largest difference between two versions of the same track.
+const durationTolerance = 2 * time.Second
+
+// minDurationTracks is the number of tracks needed to compare albums by durations:
+// singles and short EPs of similar lengths are too common to be duplicates.
+const minDurationTracks = 4
+
+// DuplicateGroup is a set of albums that seem to be the same release.
+type DuplicateGroup struct {
+	Albums  []Album
+	Reasons []string
+	Keeper  int // index in Albums of the version to keep
+}
+
+// String gives a representation of a DuplicateGroup.
+func (d *DuplicateGroup) String() (txt string) {
+	txt = "Duplicates (" + strings.Join(d.Reasons, ", ") + "):\n"
+	for i, a := range d.Albums {
+		relativePath, _ := filepath.Rel(a.Root, a.Path)
+		if i == d.Keeper {
+			txt += "\tkeep   " + relativePath + "\n"
+		} else {
+			txt += "\t       " + relativePath + "\n"
+		}
+	}
+	return
+}
+
+// normalizedName returns the main alias and title of an album, in a form suitable for comparisons.
+// FindNewPath must have been called first for aliases to be resolved.
+func (a *Album) normalizedName() string {
+	return strings.ToLower(a.mainAlias) + "|" + strings.ToLower(strings.Join(strings.Fields(a.title), " "))
+}
+
+// formatRank indicates how desirable a version of an album is, from its files.
+func formatRank(a Album, files []string) int {
+	lossless, lossy := 0, 0
+	for _, file := range files {
+		if filepath.Ext(file) == ".flac" {
+			lossless++
+		} else {
+			lossy++
+		}
+	}
+	switch {
+	case lossless != 0 && lossy == 0 && !a.IsMP3:
+		return 3
+	case lossless != 0 && lossy == 0:
+		return 2
+	case lossless != 0:
+		return 1
+	}
+	return 0
+}
+
+// unionFind groups album indexes that are found to be duplicates.
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+func (u unionFind) union(i, j int) {
+	u[u.find(i)] = u.find(j)
+}
+
+// groups returns the sets of more than one index.
+func (u unionFind) groups() (groups [][]int) {
+	members := map[int][]int{}
+	roots := []int{}
+	for i := range u {
+		root := u.find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			groups = append(groups, members[root])
+		}
+	}
+	return
+}
+
+// FindDuplicates looks for albums that are the same release, by name, by identical files or by track durations.
+func FindDuplicates(albums []Album) (duplicates []DuplicateGroup, err error) {
+	files := make([][]string, len(albums))
+	for i := range albums {
+		if files[i], err = getMusicFiles(albums[i].Path); err != nil {
+			return
+		}
+	}
+
+	// collect all groups, merging those found for different reasons
+	found := map[string]*DuplicateGroup{}
+	keys := []string{}
+	addGroups := func(reason string, u unionFind) {
+		for _, members := range u.groups() {
+			paths := []string{}
+			for _, i := range members {
+				paths = append(paths, albums[i].Path)
+			}
+			key := strings.Join(paths, "\n")
+			if group, ok := found[key]; ok {
+				group.Reasons = append(group.Reasons, reason)
+				continue
+			}
+			group := &DuplicateGroup{Reasons: []string{reason}}
+			bestRank := -1
+			for _, i := range members {
+				rank := formatRank(albums[i], files[i])
+				keeper := len(group.Albums) == 0 ||
+					rank > bestRank ||
+					(rank == bestRank && len(files[i]) > len(files[members[group.Keeper]]))
+				group.Albums = append(group.Albums, albums[i])
+				if keeper {
+					group.Keeper = len(group.Albums) - 1
+					bestRank = rank
+				}
+			}
+			found[key] = group
+			keys = append(keys, key)
+		}
+	}
+
+	// same artist and title
+	byName := newUnionFind(len(albums))
+	names := map[string]int{}
+	for i := range albums {
+		if j, ok := names[albums[i].normalizedName()]; ok {
+			byName.union(i, j)
+		} else {
+			names[albums[i].normalizedName()] = i
+		}
+	}
+	addGroups("same artist and title", byName)
+
+	// identical files: only checksum files that have the same size as another one
+	bySize := map[int64][]int{}
+	sizes := map[string]int64{}
+	for i := range albums {
+		for _, file := range files[i] {
+			fileInfo, err := os.Stat(file)
+			if err != nil {
+				return nil, err
+			}
+			if fileInfo.Size() == 0 {
+				continue
+			}
+			sizes[file] = fileInfo.Size()
+			bySize[fileInfo.Size()] = append(bySize[fileInfo.Size()], i)
+		}
+	}
+	byChecksum := newUnionFind(len(albums))
+	checksums := map[string]int{}
+	for i := range albums {
+		for _, file := range files[i] {
+			if len(bySize[sizes[file]]) < 2 {
+				continue
+			}
+			checksum, err := fileChecksum(file)
+			if err != nil {
+				return nil, err
+			}
+			if j, ok := checksums[checksum]; ok && j != i {
+				byChecksum.union(i, j)
+			} else {
+				checksums[checksum] = i
+			}
+		}
+	}
+	addGroups("identical files", byChecksum)
+
+	// same track durations
+	durations := make([][]time.Duration, len(albums))
+	for i := range albums {
+		for _, file := range files[i] {
+			info, err := ReadTrackInfo(file)
+			if err != nil || info.Duration == 0 {
+				// unreadable files or unknown durations make durations meaningless
+				durations[i] = nil
+				break
+			}
+			durations[i] = append(durations[i], info.Duration)
+		}
+	}
+	byDuration := newUnionFind(len(albums))
+	for i := range albums {
+		for j := i + 1; j < len(albums); j++ {
+			if sameDurations(durations[i], durations[j]) {
+				byDuration.union(i, j)
+			}
+		}
+	}
+	addGroups("same track durations", byDuration)
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		duplicates = append(duplicates, *found[key])
+	}
+	return
+}
+
+// sameDurations checks if two lists of track durations, long enough to be meaningful, match.
+func sameDurations(a, b []time.Duration) bool {
+	if len(a) < minDurationTracks || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		difference := a[i] - b[i]
+		if difference < -durationTolerance || difference > durationTolerance {
+			return false
+		}
+	}
+	return true
+}
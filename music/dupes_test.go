@@ -0,0 +1,59 @@
+package music
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	albums, err := getAlbums(c)
+	if err != nil {
+		t.Errorf("getAlbums returned an error: %s", err.Error())
+	}
+	duplicates, err := FindDuplicates(albums)
+	if err != nil {
+		t.Errorf("FindDuplicates returned an error: %s", err.Error())
+	}
+	if len(duplicates) != 1 {
+		t.Fatalf("FindDuplicates returned %d groups, expected 1", len(duplicates))
+	}
+	d := duplicates[0]
+	if len(d.Albums) != 2 || len(d.Reasons) != 1 || d.Reasons[0] != "same artist and title" {
+		t.Errorf("FindDuplicates returned %s, expected 2 albums with the same name", d.String())
+	}
+	expectedKeeper := filepath.Join(c.Paths.Root, "UNCATEGORIZED", "artist (2000) title2")
+	if d.Albums[d.Keeper].Path != expectedKeeper {
+		t.Errorf("FindDuplicates suggested keeping %s, expected %s", d.Albums[d.Keeper].Path, expectedKeeper)
+	}
+}
+
+var testDurations = []struct {
+	a        []time.Duration
+	b        []time.Duration
+	expected bool
+}{
+	{[]time.Duration{}, []time.Duration{}, false},
+	{testTrackDurations(4, 0), testTrackDurations(4, time.Second), true},
+	{testTrackDurations(4, 0), testTrackDurations(4, 3*time.Second), false},
+	{testTrackDurations(4, 0), testTrackDurations(5, 0), false},
+	// singles with tracks of similar lengths are not duplicates
+	{testTrackDurations(1, 0), testTrackDurations(1, time.Second), false},
+	{testTrackDurations(3, 0), testTrackDurations(3, 0), false},
+}
+
+// testTrackDurations returns n track durations, shifted by offset.
+func testTrackDurations(n int, offset time.Duration) (durations []time.Duration) {
+	for i := 0; i < n; i++ {
+		durations = append(durations, time.Duration(i+3)*time.Minute+offset)
+	}
+	return
+}
+
+func TestSameDurations(t *testing.T) {
+	for _, td := range testDurations {
+		if v := sameDurations(td.a, td.b); v != td.expected {
+			t.Errorf("sameDurations(%v, %v) returned %v, expected %v", td.a, td.b, v, td.expected)
+		}
+	}
+}
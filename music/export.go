@@ -0,0 +1,54 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/barsanuphe/radis/directory"
+	"github.com/ttacon/chalk"
+)
+
+// ExportPlaylists writes copies of a playlist, or of all playlists of MPDPlaylistDirectory if no filename is given,
+// with paths translated by an export profile.
+func ExportPlaylists(c config.Config, profileName, filename string) (err error) {
+	profile, ok := c.Profiles[profileName]
+	if !ok {
+		return errors.New("Unknown export profile: " + profileName)
+	}
+	if profile.Destination == "" {
+		return errors.New("Export profile " + profileName + " has no Destination.")
+	}
+	if err = os.MkdirAll(profile.Destination, 0777); err != nil {
+		return
+	}
+	files := []string{filename}
+	if filename == "" {
+		if files, err = directory.GetPlaylists(c.Paths.MPDPlaylistDirectory); err != nil {
+			return
+		}
+	}
+	exported := 0
+	for _, file := range files {
+		p := Playlist{Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, file)}
+		if err = p.Load(c.Paths.Root); err != nil {
+			return
+		}
+		// entries that cannot be found are kept
+		if _, err = p.Remap(c); err != nil {
+			return
+		}
+		p.Filename = filepath.Join(profile.Destination, filepath.Base(file))
+		p.Profile = &profile
+		if err := p.Write(); err != nil {
+			fmt.Println(chalk.Red.Color("!!! Could not export " + file + ": " + err.Error()))
+			continue
+		}
+		fmt.Println("Exported " + file + ".")
+		exported++
+	}
+	fmt.Printf("\n### Exported %d playlists to %s.\n", exported, profile.Destination)
+	return
+}
@@ -0,0 +1,66 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+func TestExportPlaylists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rc := config.Config{
+		Paths:  config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: dir},
+		Genres: config.Genres{config.Genre{Name: "genre", Artists: []string{"a"}}},
+		Profiles: config.ExportProfiles{
+			"laptop": {Destination: filepath.Join(dir, "laptop"), Prefix: `D:\Music`, Separator: `\`},
+			"phone":  {Destination: filepath.Join(dir, "phone"), Prefix: "/sdcard/Music", Extensions: map[string]string{".flac": ".opus"}},
+		},
+	}
+	createTestAlbums(t, rc, "genre/a/a (2000) one")
+	content := "genre/a/a (2000) one/01.flac\ngenre/a/a (1999) gone/01.flac\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "mix.m3u"), []byte(content), 0777); err != nil {
+		t.Fatal(err)
+	}
+	xspf := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist xmlns="http://xspf.org/ns/0/" version="1">
+  <trackList>
+    <track>
+      <location>genre/a/a%20(2000)%20one/01.flac</location>
+    </track>
+  </trackList>
+</playlist>
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "mix.xspf"), []byte(xspf), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, profile := range []string{"laptop", "phone"} {
+		if err := ExportPlaylists(rc, profile, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string]string{
+		"laptop/mix.m3u":  `D:\Music\genre\a\a (2000) one\01.flac`,
+		"laptop/mix.xspf": "<location>file:///D:/Music/genre/a/a%20%282000%29%20one/01.flac</location>",
+		"phone/mix.m3u":   "/sdcard/Music/genre/a/a (2000) one/01.opus\n/sdcard/Music/genre/a/a (1999) gone/01.opus\n",
+		"phone/mix.xspf":  "<location>file:///sdcard/Music/genre/a/a%20%282000%29%20one/01.opus</location>",
+	}
+	for file, location := range expected {
+		written, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(written), location) {
+			t.Errorf("ExportPlaylists wrote %s as:\n%s\nexpected %s", file, written, location)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/barsanuphe/radis/directory"
+	"github.com/ttacon/chalk"
+	"gopkg.in/yaml.v2"
+)
+
+// mirrorManifestFile keeps track of what was mirrored, in the destination directory.
+const mirrorManifestFile = ".radis_mirror.yaml"
+
+// mirrorEntry describes the source of a mirrored file, as it was when it was mirrored.
+type mirrorEntry struct {
+	Source  string `yaml:"source"`
+	ModTime int64  `yaml:"mtime"`
+	Size    int64  `yaml:"size"`
+}
+
+// mirrorStats counts what happened during a mirror sync.
+type mirrorStats struct {
+	transcoded int
+	copied     int
+	upToDate   int
+	deleted    int
+}
+
+// loadMirrorManifest reads the manifest of a mirror, if it exists.
+func loadMirrorManifest(destination string) (manifest map[string]mirrorEntry, err error) {
+	manifest = make(map[string]mirrorEntry)
+	data, err := ioutil.ReadFile(filepath.Join(destination, mirrorManifestFile))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	} else if err != nil {
+		return
+	}
+	err = yaml.Unmarshal(data, &manifest)
+	return
+}
+
+// writeMirrorManifest saves the manifest of a mirror.
+func writeMirrorManifest(destination string, manifest map[string]mirrorEntry) (err error) {
+	data, err := yaml.Marshal(&manifest)
+	if err != nil {
+		return
+	}
+	return ioutil.WriteFile(filepath.Join(destination, mirrorManifestFile), data, 0600)
+}
+
+// copyFile copies a file, overwriting the destination.
+func copyFile(source, destination string) (err error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	out, err := os.Create(destination)
+	if err != nil {
+		return
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return
+	}
+	return out.Close()
+}
+
+// removeEmptyParents removes the empty directories between a deleted file and the mirror root.
+func removeEmptyParents(path, root string) {
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if isEmpty, err := directory.IsEmpty(dir); err != nil || !isEmpty {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// syncMirror transcodes or copies the music files of albums to a destination,
+// and removes what was mirrored before but is no longer in the albums.
+func syncMirror(c config.Config, albums []Album, destination string) (stats mirrorStats, err error) {
+	if c.Mirror.Encoder == "" || c.Mirror.Extension == "" {
+		return stats, errors.New("Mirror Encoder and Extension must be configured in radis.yaml.")
+	}
+	manifest, err := loadMirrorManifest(destination)
+	if err != nil {
+		return
+	}
+	newManifest := make(map[string]mirrorEntry)
+
+	for _, a := range albums {
+		relativeAlbum, err := filepath.Rel(a.Root, a.Path)
+		if err != nil {
+			return stats, err
+		}
+		files, err := getMusicFiles(a.Path)
+		if err != nil {
+			return stats, err
+		}
+		for _, file := range files {
+			fileInfo, err := os.Stat(file)
+			if err != nil {
+				return stats, err
+			}
+			relative := filepath.Join(relativeAlbum, filepath.Base(file))
+			isLossy := c.FilePolicy.Classify(a.genre, file) == config.AudioLossy
+			if !isLossy {
+				relative = strings.TrimSuffix(relative, filepath.Ext(relative)) + c.Mirror.Extension
+			}
+			entry := mirrorEntry{Source: file, ModTime: fileInfo.ModTime().UnixNano(), Size: fileInfo.Size()}
+			output := filepath.Join(destination, relative)
+
+			// skip if already up to date
+			if previous, ok := manifest[relative]; ok && previous == entry {
+				if _, err := os.Stat(output); err == nil {
+					newManifest[relative] = entry
+					stats.upToDate++
+					continue
+				}
+			}
+
+			if err := os.MkdirAll(filepath.Dir(output), 0777); err != nil {
+				return stats, err
+			}
+			if isLossy {
+				if err := copyFile(file, output); err != nil {
+					return stats, err
+				}
+				fmt.Println(chalk.Green.Color("+ Copied " + relative))
+				stats.copied++
+			} else {
+				command := c.Mirror.EncoderCommand(file, output)
+				if out, err := exec.Command(command[0], command[1:]...).CombinedOutput(); err != nil {
+					os.Remove(output)
+					return stats, errors.New("Could not transcode " + file + ": " + err.Error() + "\n" + string(out))
+				}
+				fmt.Println(chalk.Green.Color("+ Transcoded " + relative))
+				stats.transcoded++
+			}
+			newManifest[relative] = entry
+		}
+		// save progress after each album, in case of interruption
+		if err := writeMirrorManifest(destination, mergeManifests(manifest, newManifest)); err != nil {
+			return stats, err
+		}
+	}
+
+	// remove files that have disappeared from the source
+	obsolete := []string{}
+	for relative := range manifest {
+		if _, ok := newManifest[relative]; !ok {
+			obsolete = append(obsolete, relative)
+		}
+	}
+	sort.Strings(obsolete)
+	for _, relative := range obsolete {
+		output := filepath.Join(destination, relative)
+		if err := os.Remove(output); err != nil && !os.IsNotExist(err) {
+			return stats, err
+		}
+		fmt.Println(chalk.Yellow.Color("- Deleted " + relative))
+		removeEmptyParents(output, destination)
+		stats.deleted++
+	}
+	err = writeMirrorManifest(destination, newManifest)
+	return
+}
+
+// mergeManifests adds the files mirrored so far to the previous manifest,
+// so that an interrupted mirror can still delete obsolete files the next time.
+func mergeManifests(previous, current map[string]mirrorEntry) map[string]mirrorEntry {
+	merged := make(map[string]mirrorEntry)
+	for k, v := range previous {
+		merged[k] = v
+	}
+	for k, v := range current {
+		merged[k] = v
+	}
+	return merged
+}
+
+// MirrorCollection recreates the albums of the selected genres under a destination, transcoding lossless files.
+func MirrorCollection(c config.Config, destination string) (err error) {
+	defer timeTrack(time.Now(), "Mirroring files")
+
+	destination, err = filepath.Abs(destination)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(destination, 0777); err != nil {
+		return
+	}
+	fmt.Printf("%sMirroring %s to %s...\n\n%s", chalk.Blue, c.Paths.Root, destination, chalk.Reset)
+	albums, err := getAlbums(c)
+	if err != nil {
+		return
+	}
+	selected := []Album{}
+	for _, a := range albums {
+		if c.Mirror.HasGenre(a.genre) {
+			selected = append(selected, a)
+		}
+	}
+	stats, err := syncMirror(c, selected, destination)
+	fmt.Printf("\n### Mirrored %d albums: %d files transcoded, %d copied, %d up to date, %d deleted.\n",
+		len(selected), stats.transcoded, stats.copied, stats.upToDate, stats.deleted)
+	return
+}
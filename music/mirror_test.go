@@ -0,0 +1,77 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+func TestSyncMirror(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// fake encoder, which only copies the file
+	encoder := filepath.Join(dir, "encoder.sh")
+	if err := ioutil.WriteFile(encoder, []byte("#!/bin/sh\ncp \"$1\" \"$2\"\n"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	mc := config.Config{
+		Paths:      config.Paths{Root: filepath.Join(dir, "music"), UnsortedSubdir: "UNCATEGORIZED"},
+		Genres:     config.Genres{config.Genre{Name: "genre1", Artists: []string{"artist"}}},
+		Mirror:     config.Mirror{Genres: []string{"genre1"}, Encoder: "sh " + encoder + " {input} {output}", Extension: ".opus"},
+		FilePolicy: config.DefaultFilePolicy(),
+	}
+	album := filepath.Join(mc.Paths.Root, "genre1", "artist", "artist (2000) title")
+	if err := os.MkdirAll(album, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"01.flac", "02.mp3"} {
+		if err := ioutil.WriteFile(filepath.Join(album, file), []byte(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	albums, err := getAlbums(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination := filepath.Join(dir, "mirror")
+	relativeAlbum := filepath.Join("genre1", "artist", "artist (2000) title")
+
+	// first mirror
+	stats, err := syncMirror(mc, albums, destination)
+	if err != nil {
+		t.Errorf("syncMirror returned an error: %s", err.Error())
+	}
+	if stats.transcoded != 1 || stats.copied != 1 {
+		t.Errorf("syncMirror returned %+v, expected 1 transcoded and 1 copied file", stats)
+	}
+	for _, file := range []string{"01.opus", "02.mp3"} {
+		if _, err := os.Stat(filepath.Join(destination, relativeAlbum, file)); err != nil {
+			t.Errorf("syncMirror did not create %s", file)
+		}
+	}
+
+	// nothing to do the second time
+	stats, err = syncMirror(mc, albums, destination)
+	if err != nil || stats.upToDate != 2 || stats.transcoded != 0 || stats.copied != 0 {
+		t.Errorf("syncMirror returned %+v, expected 2 files up to date", stats)
+	}
+
+	// removed source files are deleted from the mirror
+	if err := os.Remove(filepath.Join(album, "02.mp3")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = syncMirror(mc, albums, destination)
+	if err != nil || stats.deleted != 1 || stats.upToDate != 1 {
+		t.Errorf("syncMirror returned %+v, expected 1 file deleted", stats)
+	}
+	if _, err := os.Stat(filepath.Join(destination, relativeAlbum, "02.mp3")); !os.IsNotExist(err) {
+		t.Errorf("syncMirror did not delete 02.mp3")
+	}
+}
@@ -0,0 +1,182 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/barsanuphe/radis/directory"
+	"github.com/barsanuphe/radis/mpd"
+	"github.com/ttacon/chalk"
+)
+
+// connectToMPD opens a connection with the settings of radis.yaml.
+func connectToMPD(c config.Config) (*mpd.Client, error) {
+	return mpd.Dial(c.MPD.Network, c.MPD.Address, c.MPD.Password)
+}
+
+// mpdDirectories returns the directories MPD must rescan after albums have moved:
+// the closest existing ancestors of their old and new paths, relative to the root, without nested ones.
+// An empty string stands for the whole collection.
+func mpdDirectories(root string, paths []string) (directories []string) {
+	candidates := make(map[string]bool)
+	for _, path := range paths {
+		for ; path != root && strings.HasPrefix(path, root); path = filepath.Dir(path) {
+			if _, err := os.Stat(path); err == nil {
+				break
+			}
+		}
+		relativePath, err := filepath.Rel(root, path)
+		if err != nil || relativePath == "." || isOutsideRoot(relativePath) {
+			return []string{""}
+		}
+		candidates[relativePath] = true
+	}
+	sorted := []string{}
+	for path := range candidates {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	for _, path := range sorted {
+		if len(directories) != 0 && strings.HasPrefix(path, directories[len(directories)-1]+string(filepath.Separator)) {
+			continue
+		}
+		directories = append(directories, path)
+	}
+	return
+}
+
+// UpdateMPDDatabase asks MPD to rescan the directories where albums have moved, and waits until it is done.
+// paths are absolute paths of albums, before and after moving.
+func UpdateMPDDatabase(c config.Config, paths []string) (err error) {
+	if len(paths) == 0 {
+		return
+	}
+	client, err := connectToMPD(c)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	job := 0
+	for _, dir := range mpdDirectories(c.Paths.Root, paths) {
+		if dir == "" {
+			fmt.Println("Updating the MPD database.")
+		} else {
+			fmt.Println("Updating the MPD database for " + dir + ".")
+		}
+		if job, err = client.Update(dir); err != nil {
+			return
+		}
+	}
+	if err = client.WaitForUpdate(job, c.MPD.Timeout()); err != nil {
+		return
+	}
+	fmt.Println("MPD database updated.")
+	return
+}
+
+// CheckMPDPlaylists makes MPD read the playlists of MPDPlaylistDirectory, and lists those it cannot read.
+// MPD reads stored playlists when they are used, so this is enough to reload them.
+func CheckMPDPlaylists(c config.Config) (err error) {
+	client, err := connectToMPD(c)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	stored, err := client.ListPlaylists()
+	if err != nil {
+		return
+	}
+	known := make(map[string]bool)
+	for _, name := range stored {
+		known[name] = true
+	}
+	files, err := directory.GetPlaylists(c.Paths.MPDPlaylistDirectory)
+	if err != nil {
+		return
+	}
+	problems := 0
+	for _, file := range files {
+		name := strings.TrimSuffix(file, filepath.Ext(file))
+		if !known[name] {
+			// MPD only knows .m3u playlists
+			continue
+		}
+		if _, err := client.ListPlaylist(name); err != nil {
+			fmt.Println(chalk.Red.Color("!!! MPD could not read playlist " + file + ": " + err.Error()))
+			problems++
+		}
+	}
+	fmt.Printf("### MPD knows %d playlists, %d could not be read.\n", len(stored), problems)
+	return
+}
+
+// EnqueueAlbums appends albums to the MPD queue, after MPD has finished updating its database.
+// Albums are added in the order they are given, unless by is set.
+func EnqueueAlbums(c config.Config, albums []Album, by string, reverse bool, seed int64) (err error) {
+	if !c.MPD.IsConfigured() {
+		return errors.New("MPD must be configured in radis.yaml.")
+	}
+	if len(albums) == 0 {
+		fmt.Println("No new albums to enqueue.")
+		return
+	}
+	p := Playlist{AlbumLevel: true}
+	for _, a := range albums {
+		p.AddAlbum(a)
+	}
+	if by != "" {
+		if err = p.Order(c, by, reverse, seed); err != nil {
+			return
+		}
+	}
+
+	client, err := connectToMPD(c)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	// the files of new albums must be in the database
+	if err = client.WaitForUpdate(0, c.MPD.Timeout()); err != nil {
+		return
+	}
+	added := 0
+	for _, t := range p.contents {
+		files, err := t.Files()
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			relativePath, err := filepath.Rel(c.Paths.Root, file)
+			if err != nil {
+				return err
+			}
+			if err = client.Add(relativePath); err != nil {
+				return err
+			}
+			added++
+		}
+		fmt.Println(chalk.Green.Color("+ Enqueued " + t.Album.String()))
+	}
+	fmt.Printf("### Enqueued %d albums, %d tracks.\n", len(p.contents), added)
+	return
+}
+
+// EnqueueNewAlbums appends the albums of the first rolling playlist of the current period to the MPD queue.
+func EnqueueNewAlbums(c config.Config, by string, reverse bool, seed int64) (err error) {
+	if len(c.Rolling) == 0 {
+		return errors.New("No rolling playlists to find new albums in.")
+	}
+	current := loadRollingPlaylists(c)[0]
+	albums := []Album{}
+	for _, t := range current.contents {
+		if !t.missing {
+			albums = append(albums, t.Album)
+		}
+	}
+	return EnqueueAlbums(c, albums, by, reverse, seed)
+}
@@ -0,0 +1,115 @@
+package music
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+func TestMPDDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, d := range []string{"Jazz/Miles/Miles (1959) Blue", "Rock/Band", "UNCATEGORIZED", "..Interludes"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{
+		// moved from an artist directory that was removed
+		filepath.Join(dir, "UNCATEGORIZED/Miles/Miles (1959) Blue"),
+		filepath.Join(dir, "Jazz/Miles/Miles (1959) Blue"),
+		filepath.Join(dir, "Rock/Band/Band (1960) Loud"),
+		filepath.Join(dir, "Jazz/Miles"),
+		filepath.Join(dir, "..Interludes/Band (1961) Quiet"),
+	}
+	expected := []string{"..Interludes", "Jazz/Miles", "Rock/Band", "UNCATEGORIZED"}
+	if v := mpdDirectories(dir, paths); !reflect.DeepEqual(v, expected) {
+		t.Errorf("mpdDirectories returned %v, expected %v", v, expected)
+	}
+	if v := mpdDirectories(dir, []string{filepath.Join(dir, "gone/a/b")}); !reflect.DeepEqual(v, []string{""}) {
+		t.Errorf("mpdDirectories returned %q, expected the whole collection", v)
+	}
+}
+
+// fakeMPD accepts one connection, answers OK to every command, and sends the commands it receives.
+func fakeMPD(t *testing.T) (address string, received chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received = make(chan string, 100)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("OK MPD 0.21.0\n"))
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || line == "close\n" {
+				close(received)
+				return
+			}
+			received <- strings.TrimSuffix(line, "\n")
+			conn.Write([]byte("OK\n"))
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestEnqueueAlbums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	address, received := fakeMPD(t)
+	ec := config.Config{
+		Paths: config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED"},
+		MPD:   config.MPD{Network: "tcp", Address: address},
+	}
+	albums := []Album{}
+	for _, album := range []string{"genre/b/b (2001) two", "genre/a/a (2000) one"} {
+		path := filepath.Join(dir, album)
+		if err := os.MkdirAll(path, 0777); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range []string{"01.flac", "02.flac"} {
+			if err := ioutil.WriteFile(filepath.Join(path, file), []byte{}, 0777); err != nil {
+				t.Fatal(err)
+			}
+		}
+		albums = append(albums, Album{Root: dir, Path: path, NewPath: path})
+	}
+
+	if err := EnqueueAlbums(ec, albums, config.OrderYear, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	commands := []string{}
+	for c := range received {
+		commands = append(commands, c)
+	}
+	expected := []string{
+		"status",
+		`add "genre/a/a (2000) one/01.flac"`, `add "genre/a/a (2000) one/02.flac"`,
+		`add "genre/b/b (2001) two/01.flac"`, `add "genre/b/b (2001) two/02.flac"`,
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("EnqueueAlbums sent %v, expected %v", commands, expected)
+	}
+}
@@ -6,22 +6,71 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/barsanuphe/radis/config"
 	"github.com/barsanuphe/radis/directory"
+	"github.com/ttacon/chalk"
 )
 
-// Playlist can generate .m3u playlists from a list of AlbumFolders.
-type Playlist struct {
+// Track is an entry of a Playlist: a file of an album, or the whole album if Filename is empty.
+// Filename is relative to the album directory.
+// entry is what the playlist the Track was loaded from said about it, if anything.
+type Track struct {
+	Album    Album
 	Filename string
-	contents []Album
+	missing  bool
+	entry    *playlistEntry
+}
+
+// String gives a representation of a Track.
+func (t *Track) String() string {
+	if t.IsAlbum() {
+		return t.Album.Path
+	}
+	return filepath.Join(t.Album.Path, t.Filename)
+}
+
+// IsAlbum indicates if the Track stands for a whole album.
+func (t *Track) IsAlbum() bool {
+	return t.Filename == ""
+}
+
+// Files returns the music files of a Track, in the album's NewPath.
+func (t *Track) Files() (files []string, err error) {
+	if t.IsAlbum() {
+		return t.Album.GetMusicFiles()
+	}
+	file := filepath.Join(t.Album.NewPath, t.Filename)
+	existing := file
+	if isVirtualTrack(file) {
+		existing = filepath.Dir(file)
+	}
+	if _, err = os.Stat(existing); err != nil {
+		return
+	}
+	return []string{file}, nil
+}
+
+// Playlist can generate .m3u, .xspf or .pls playlists from a list of Tracks.
+// Extended m3u playlists have #EXTM3U and #EXTINF directives.
+// AlbumLevel playlists only keep whole albums, all their files are written.
+// Paths are written relative to the collection root, unless a Profile translates them for another device.
+type Playlist struct {
+	Filename   string
+	Extended   bool
+	AlbumLevel bool
+	Profile    *config.ExportProfile
+	contents   []Track
 }
 
 // String gives a representation of an Playlist.
 func (p *Playlist) String() (playlist string) {
-	playlist = fmt.Sprintf("%s: %d albums", p.Filename, len(p.contents))
+	if p.AlbumLevel {
+		playlist = fmt.Sprintf("%s: %d albums", p.Filename, len(p.contents))
+	} else {
+		playlist = fmt.Sprintf("%s: %d tracks", p.Filename, len(p.contents))
+	}
 	return
 }
 
@@ -31,20 +80,25 @@ func (p *Playlist) Exists() (isPlaylist bool, err error) {
 	if err != nil {
 		return
 	}
-	if filepath.Ext(path) == ".m3u" {
+	if _, err := playlistFormat(path); err == nil {
 		isPlaylist = true
 	}
 	return
 }
 
+// AddAlbum adds a whole album to the Playlist.
+func (p *Playlist) AddAlbum(a Album) {
+	p.contents = append(p.contents, Track{Album: a})
+}
+
 // RemoveDuplicates in a Playlist's Contents
 func (p *Playlist) RemoveDuplicates() (err error) {
-	result := []Album{}
-	seen := map[string]Album{}
+	result := []Track{}
+	seen := map[string]Track{}
 	for _, val := range p.contents {
-		if _, ok := seen[val.Path]; !ok {
+		if _, ok := seen[val.String()]; !ok {
 			result = append(result, val)
-			seen[val.Path] = val
+			seen[val.String()] = val
 		}
 	}
 	// replace contents
@@ -52,22 +106,13 @@ func (p *Playlist) RemoveDuplicates() (err error) {
 	return
 }
 
-// removeDuplicatePaths takes a slice of paths, return one without duplicates
-func removeDuplicatePaths(a []string) []string {
-	result := []string{}
-	seen := map[string]string{}
-	for _, val := range a {
-		if _, ok := seen[val]; !ok {
-			result = append(result, val)
-			seen[val] = val
-		}
-	}
-	return result
-}
-
 // Load an existing playlist
 func (p *Playlist) Load(root string) (err error) {
-	// open file and get strings
+	format, err := playlistFormat(p.Filename)
+	if err != nil {
+		return
+	}
+	// open file and get entries
 	content, err := ioutil.ReadFile(p.Filename)
 	if os.IsNotExist(err) {
 		// file does not exist, nothing to do
@@ -75,43 +120,103 @@ func (p *Playlist) Load(root string) (err error) {
 	} else if err != nil {
 		return err
 	}
-	lines := strings.Split(string(content), "\n")
-	//remove filename
-	albumsPaths := []string{}
-	for _, l := range lines {
-		if l != "" {
-			albumsPaths = append(albumsPaths, filepath.Dir(l))
+	entries, extended, err := readPlaylistEntries(format, content)
+	if err != nil {
+		return
+	}
+	p.Extended = p.Extended || extended
+	tracks := []Track{}
+	for _, e := range entries {
+		l := e.Path
+		if filepath.IsAbs(l) {
+			// MPD wants relative paths
+			if relativePath, err := filepath.Rel(root, l); err == nil {
+				l = relativePath
+			}
 		}
+		// separate album directory and filename
+		albumPath, filename := filepath.Dir(l), filepath.Base(l)
+		if isVirtualTrack(l) {
+			// album.cue/track0001
+			albumPath = filepath.Dir(albumPath)
+			filename = filepath.Join(filepath.Base(filepath.Dir(l)), filename)
+		}
+		a := Album{Root: root, Path: albumPath, NewPath: filepath.Join(root, albumPath)}
+		if p.AlbumLevel {
+			tracks = append(tracks, Track{Album: a})
+			continue
+		}
+		entry := e
+		tracks = append(tracks, Track{Album: a, Filename: filename, entry: &entry})
 	}
+	p.contents = append(p.contents, tracks...)
 	// remove duplicates
-	albumsPaths = removeDuplicatePaths(albumsPaths)
-
-	// add AlbumFolder to Contents
-	for _, a := range albumsPaths {
-		p.contents = append(p.contents, Album{Root: root, Path: a})
-	}
-	return
+	return p.RemoveDuplicates()
 }
 
-// Update a playlist by parsing the AlbumFolders it contains and writing their new paths
+// Update a playlist by parsing the albums it contains and writing their new paths.
+// Tracks that are not in an album are kept as they are.
 func (p *Playlist) Update(c config.Config) (err error) {
 	if len(p.contents) == 0 {
 		// nothing to do
 		return
 	}
 	for i := range p.contents {
-		if !p.contents[i].IsValidAlbum() {
-			err = errors.New(p.contents[i].Path + " does not seem to be an album!")
-			return
+		if !p.contents[i].Album.IsValidAlbum() {
+			p.contents[i].missing = true
+			continue
 		}
 		// find the new path, so that it can be exported by Write
-		if _, err := p.contents[i].FindNewPath(c); err != nil {
+		if _, err := p.contents[i].Album.FindNewPath(c); err != nil {
 			panic(err)
 		}
 	}
 	return
 }
 
+// UpdateReport counts what happened to the entries of a playlist during an update.
+type UpdateReport struct {
+	Remapped     int
+	Unchanged    int
+	Unresolvable int
+}
+
+func (r UpdateReport) String() string {
+	return fmt.Sprintf("%d remapped, %d unchanged, %d unresolvable", r.Remapped, r.Unchanged, r.Unresolvable)
+}
+
+// Remap points the tracks of a loaded playlist to where their albums are now.
+// Tracks that cannot be found are kept as they are.
+func (p *Playlist) Remap(c config.Config) (report UpdateReport, err error) {
+	for i := range p.contents {
+		t := &p.contents[i]
+		original := t.Album.NewPath
+		if !t.Album.IsValidAlbum() {
+			t.missing = true
+			report.Unresolvable++
+			continue
+		}
+		if _, err = t.Album.FindNewPath(c); err != nil {
+			return
+		}
+		if _, err := t.Files(); err != nil {
+			// maybe not synced yet
+			t.Album.NewPath = original
+			if _, err := t.Files(); err != nil {
+				t.missing = true
+				report.Unresolvable++
+				continue
+			}
+		}
+		if t.Album.NewPath != original {
+			report.Remapped++
+		} else {
+			report.Unchanged++
+		}
+	}
+	return
+}
+
 // Write the playlist or append it if it exists
 func (p *Playlist) Write() (err error) {
 	if len(p.contents) == 0 {
@@ -124,115 +229,288 @@ func (p *Playlist) Write() (err error) {
 		return err
 	}
 
+	format, err := playlistFormat(p.Filename)
+	if err != nil {
+		return
+	}
+	// only m3u playlists can do without tags
+	withInfo := p.Extended || format != m3uFormat
+
 	// append contents
-	contents := []string{}
-	for _, af := range p.contents {
-		files, err := af.GetMusicFiles()
+	entries := []playlistEntry{}
+	written := make(map[string]string)
+	for _, t := range p.contents {
+		if t.missing {
+			// keep what could not be found, so that it can be repaired later
+			path := t.String()
+			if p.Profile != nil {
+				path = p.Profile.Translate(t.Album.Root, path, format == xspfFormat)
+			}
+			e := playlistEntry{Duration: -1}
+			if t.entry != nil {
+				e = *t.entry
+			}
+			e.Path = path
+			entries = append(entries, e)
+			continue
+		}
+		files, err := t.Files()
 		if os.IsNotExist(err) {
-			return errors.New("Could not find path " + af.NewPath + "; have you synced lately?")
+			return errors.New("Could not find path " + t.Album.NewPath + "; have you synced lately?")
 		} else if err != nil {
 			return err
 		}
 		for i := range files {
 			// MPD wants relative paths
-			relativePath, err := filepath.Rel(af.Root, files[i])
+			relativePath, err := filepath.Rel(t.Album.Root, files[i])
 			if err != nil {
 				panic(err)
 			}
-			contents = append(contents, relativePath)
+			written[relativePath] = files[i]
+			if p.Profile != nil {
+				relativePath = p.Profile.Translate(t.Album.Root, relativePath, format == xspfFormat)
+			}
+			entries = append(entries, newPlaylistEntry(relativePath, files[i], withInfo, t.entry))
 		}
 	}
 
 	// write if everything is good.
-	f, err := os.OpenFile(p.Filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	data, err := writePlaylistEntries(format, entries, p.Extended)
 	if err != nil {
 		return
 	}
-	defer f.Close()
-	for _, file := range contents {
-		if _, err = f.WriteString(file + "\n"); err != nil {
-			return err
+	if err = ioutil.WriteFile(p.Filename, data, 0600); err != nil {
+		return
+	}
+	// exported playlists are for other devices
+	if p.Profile == nil {
+		err = recordChecksums(filepath.Dir(p.Filename), written)
+	}
+	return
+}
+
+// replaceAlbums points albums that were renamed to their new directory, and returns how many were found.
+// renamed maps absolute old paths to absolute new paths.
+func (p *Playlist) replaceAlbums(root string, renamed map[string]string) (replaced int) {
+	for i := range p.contents {
+		a := &p.contents[i].Album
+		currentPath := a.Path
+		if !filepath.IsAbs(currentPath) {
+			currentPath = filepath.Join(root, currentPath)
+		}
+		if newPath, ok := renamed[currentPath]; ok {
+			*a = Album{Root: root, Path: newPath, NewPath: newPath}
+			replaced++
+		} else if a.NewPath == "" {
+			// not renamed, stays where it is
+			a.NewPath = currentPath
 		}
 	}
 	return
 }
 
+// renameInPlaylists updates the playlists of MPDPlaylistDirectory that contain renamed albums.
+func renameInPlaylists(c config.Config, renamed map[string]string) (err error) {
+	if len(renamed) == 0 {
+		return
+	}
+	files, err := directory.GetPlaylists(c.Paths.MPDPlaylistDirectory)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		p := Playlist{Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, file)}
+		if err = p.Load(c.Paths.Root); err != nil {
+			return
+		}
+		if replaced := p.replaceAlbums(c.Paths.Root, renamed); replaced != 0 {
+			if err = p.Write(); err != nil {
+				fmt.Println("Could not update playlist " + file + ": " + err.Error())
+				continue
+			}
+			fmt.Printf("Updated %d albums in playlist %s.\n", replaced, file)
+		}
+	}
+	return nil
+}
+
 // UpdateAndSave a Playlist file.
-func (p *Playlist) UpdateAndSave(c config.Config) (err error) {
+// It is only written if some tracks have moved, unless Extended was set to add directives.
+func (p *Playlist) UpdateAndSave(c config.Config) (report UpdateReport, err error) {
 	isPlaylist, err := p.Exists()
 	if err != nil {
 		return
 	} else if !isPlaylist {
-		return errors.New(p.Filename + "does not exist!")
+		return report, errors.New(p.Filename + " does not exist!")
 	}
+	addDirectives := p.Extended
 	// Load the playlist
 	err = p.Load(c.Paths.Root)
 	if err != nil {
-		panic(err)
+		return
 	}
 	// Update the playlist
-	err = p.Update(c)
+	report, err = p.Remap(c)
 	if err != nil {
-		panic(err)
+		return
+	}
+	if report.Remapped == 0 && !addDirectives {
+		return
 	}
 	// Write the playlist
 	err = p.Write()
+	return
+}
+
+// UpdateAllPlaylists updates every playlist of MPDPlaylistDirectory after albums have moved.
+func UpdateAllPlaylists(c config.Config) (err error) {
+	files, err := directory.GetPlaylists(c.Paths.MPDPlaylistDirectory)
 	if err != nil {
 		return
 	}
+	total := UpdateReport{}
+	for _, file := range files {
+		p := Playlist{Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, file)}
+		report, err := p.UpdateAndSave(c)
+		if err != nil {
+			fmt.Println(chalk.Red.Color("!!! Could not update playlist " + file + ": " + err.Error()))
+			continue
+		}
+		switch {
+		case report.Unresolvable != 0:
+			fmt.Println(chalk.Red.Color(file + ": " + report.String()))
+		case report.Remapped != 0:
+			fmt.Println(chalk.Yellow.Color(file + ": " + report.String()))
+		default:
+			fmt.Println(file + ": " + report.String())
+		}
+		total.Remapped += report.Remapped
+		total.Unchanged += report.Unchanged
+		total.Unresolvable += report.Unresolvable
+	}
+	fmt.Printf("\n### Updated %d playlists: %s.\n", len(files), total.String())
 	return
 }
 
-// loadCurrentPlaylists finds and loads current playlists
-func loadCurrentPlaylists(c config.Config) (daily Playlist, monthly Playlist) {
+// loadRollingPlaylists finds and loads the rolling playlists of the current periods.
+func loadRollingPlaylists(c config.Config) (playlists []Playlist) {
 	now := time.Now().Local()
-	thisDay := now.Format("2006-01-02")
-	thisMonth := now.Format("2006-01")
-
-	daily = Playlist{
-		Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, thisDay+".m3u"),
-	}
-	monthly = Playlist{
-		Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, thisMonth+".m3u"),
+	for _, r := range c.Rolling {
+		p := Playlist{
+			Filename:   filepath.Join(c.Paths.MPDPlaylistDirectory, r.FilenameAt(now)),
+			AlbumLevel: true,
+		}
+		// Load the playlist if it exists
+		if err := p.Load(c.Paths.Root); err != nil {
+			panic(err)
+		}
+		// Update the playlist if it exists
+		if err := p.Update(c); err != nil {
+			panic(err)
+		}
+		playlists = append(playlists, p)
 	}
+	return
+}
 
-	// Load the playlists if they exist
-	err := daily.Load(c.Paths.Root)
-	if err != nil {
-		panic(err)
+// keepLast removes all but the last n entries of a playlist.
+func (p *Playlist) keepLast(n int) (err error) {
+	if err = p.RemoveDuplicates(); err != nil {
+		return
 	}
-	err = monthly.Load(c.Paths.Root)
-	if err != nil {
-		panic(err)
+	if len(p.contents) > n {
+		p.contents = p.contents[len(p.contents)-n:]
 	}
+	return
+}
 
-	// Update the playlists if they exist
-	err = daily.Update(c)
+// expireRollingPlaylists deletes or archives the playlists of a RollingPlaylist that are older than the periods to keep.
+func expireRollingPlaylists(c config.Config, r config.RollingPlaylist, now time.Time) (err error) {
+	files, err := directory.GetPlaylists(c.Paths.MPDPlaylistDirectory)
 	if err != nil {
-		panic(err)
+		return
 	}
-	err = monthly.Update(c)
-	if err != nil {
-		panic(err)
+	archive := r.Archive
+	if archive != "" && !filepath.IsAbs(archive) {
+		archive = filepath.Join(c.Paths.MPDPlaylistDirectory, archive)
 	}
-	return
-}
-
-// writePlaylists after sync
-func writeCurrentPlaylists(daily Playlist, monthly Playlist) (err error) {
-	if len(daily.contents) != 0 {
-		fmt.Println("Writing playlist " + filepath.Base(daily.Filename) + ".")
-		err = daily.Write()
+	for _, file := range files {
+		if !r.IsExpired(file, now) {
+			continue
+		}
+		path := filepath.Join(c.Paths.MPDPlaylistDirectory, file)
+		if archive == "" {
+			fmt.Println("Deleting expired playlist " + file + ".")
+			err = os.Remove(path)
+		} else {
+			fmt.Println("Archiving expired playlist " + file + ".")
+			if err = os.MkdirAll(archive, 0777); err != nil {
+				return
+			}
+			err = os.Rename(path, filepath.Join(archive, file))
+		}
 		if err != nil {
 			return
 		}
 	}
-	if len(monthly.contents) != 0 {
-		fmt.Println("Writing playlist " + filepath.Base(monthly.Filename) + ".")
-		err = monthly.Write()
-		if err != nil {
+	return
+}
+
+// writeRollingPlaylists after sync, and expire the playlists of previous periods.
+func writeRollingPlaylists(c config.Config, playlists []Playlist) (err error) {
+	now := time.Now().Local()
+	for i, r := range c.Rolling {
+		p := playlists[i]
+		if r.Period == config.Last {
+			if err = p.keepLast(r.Count); err != nil {
+				return
+			}
+		}
+		if r.Order != "" {
+			if err = p.Order(c, r.Order, false, now.UnixNano()); err != nil {
+				return
+			}
+		}
+		if len(p.contents) != 0 {
+			fmt.Println("Writing playlist " + filepath.Base(p.Filename) + ".")
+			if err = p.Write(); err != nil {
+				return
+			}
+		}
+		if err = expireRollingPlaylists(c, r, now); err != nil {
 			return
 		}
 	}
 	return
 }
+
+// ConvertPlaylist writes a playlist of MPDPlaylistDirectory in the format of output.
+// Entries that cannot be found are kept, with what the input playlist said about them.
+func ConvertPlaylist(c config.Config, input, output string) (err error) {
+	if !filepath.IsAbs(input) {
+		input = filepath.Join(c.Paths.MPDPlaylistDirectory, input)
+	}
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(c.Paths.MPDPlaylistDirectory, output)
+	}
+	if _, err = playlistFormat(output); err != nil {
+		return
+	}
+	p := Playlist{Filename: input}
+	if isPlaylist, err := p.Exists(); err != nil || !isPlaylist {
+		return errors.New(input + " is not a playlist.")
+	}
+	fmt.Println("Converting " + input + " to " + output)
+	if err = p.Load(c.Paths.Root); err != nil {
+		return
+	}
+	report, err := p.Remap(c)
+	if err != nil {
+		return
+	}
+	if report.Unresolvable != 0 {
+		fmt.Printf("Keeping %d entries that could not be found.\n", report.Unresolvable)
+	}
+	p.Filename = output
+	return p.Write()
+}
@@ -0,0 +1,153 @@
+package music
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/barsanuphe/radis/directory"
+	"github.com/ttacon/chalk"
+)
+
+// Problems found in playlist entries.
+const (
+	problemUnreadable  = "unreadable playlist"
+	problemMissing     = "missing file"
+	problemOutsideRoot = "outside of root"
+	problemAbsolute    = "absolute path"
+	problemDuplicate   = "duplicate entry"
+	problemInvalidUTF8 = "invalid UTF-8"
+)
+
+// PlaylistProblem is a problem found in a playlist entry.
+type PlaylistProblem struct {
+	Entry   string `json:"entry"`
+	Problem string `json:"problem"`
+}
+
+// PlaylistCheck lists the problems found in a playlist.
+type PlaylistCheck struct {
+	Playlist string            `json:"playlist"`
+	Entries  int               `json:"entries"`
+	Problems []PlaylistProblem `json:"problems"`
+}
+
+// String gives a representation of a PlaylistCheck.
+func (pc *PlaylistCheck) String() (txt string) {
+	txt = fmt.Sprintf("%s: %d entries, %d problems\n", pc.Playlist, pc.Entries, len(pc.Problems))
+	for _, p := range pc.Problems {
+		txt += "\t" + p.Problem + ": " + p.Entry + "\n"
+	}
+	return
+}
+
+// isStream indicates if a playlist entry is a URL rather than a file.
+func isStream(entry string) bool {
+	return strings.Contains(entry, "://")
+}
+
+// isOutsideRoot indicates if a path relative to the root leads out of it.
+// Names that merely start with "..", such as "..Interlude", are inside.
+func isOutsideRoot(relativePath string) bool {
+	return relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// checkEntry returns the problems of a playlist entry, other than duplicates.
+func checkEntry(root, entry string) (problems []string) {
+	if !utf8.ValidString(entry) {
+		problems = append(problems, problemInvalidUTF8)
+	}
+	path := entry
+	if filepath.IsAbs(entry) {
+		problems = append(problems, problemAbsolute)
+	} else {
+		path = filepath.Join(root, entry)
+	}
+	if relativePath, err := filepath.Rel(root, path); err != nil || isOutsideRoot(relativePath) {
+		return append(problems, problemOutsideRoot)
+	}
+	existing := path
+	if isVirtualTrack(path) {
+		existing = filepath.Dir(path)
+	}
+	if _, err := os.Stat(existing); err != nil {
+		problems = append(problems, problemMissing)
+	}
+	return
+}
+
+// CheckPlaylist parses a playlist without modifying it and lists its problems.
+func CheckPlaylist(c config.Config, filename string) (check PlaylistCheck, err error) {
+	check = PlaylistCheck{Playlist: filepath.Base(filename), Problems: []PlaylistProblem{}}
+	format, err := playlistFormat(filename)
+	if err != nil {
+		return
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	entries, _, err := readPlaylistEntries(format, data)
+	if err != nil {
+		check.Problems = append(check.Problems, PlaylistProblem{Entry: err.Error(), Problem: problemUnreadable})
+		return check, nil
+	}
+	check.Entries = len(entries)
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if isStream(e.Path) {
+			continue
+		}
+		for _, problem := range checkEntry(c.Paths.Root, e.Path) {
+			check.Problems = append(check.Problems, PlaylistProblem{Entry: e.Path, Problem: problem})
+		}
+		if seen[filepath.Clean(e.Path)] {
+			check.Problems = append(check.Problems, PlaylistProblem{Entry: e.Path, Problem: problemDuplicate})
+		}
+		seen[filepath.Clean(e.Path)] = true
+	}
+	return
+}
+
+// CheckPlaylists checks a playlist, or all playlists of MPDPlaylistDirectory if no filename is given.
+// Results are printed for humans or as JSON. It returns the number of problems found.
+func CheckPlaylists(c config.Config, filename string, asJSON bool) (problems int, err error) {
+	files := []string{filename}
+	if filename == "" {
+		if files, err = directory.GetPlaylists(c.Paths.MPDPlaylistDirectory); err != nil {
+			return
+		}
+	}
+	checks := []PlaylistCheck{}
+	for _, file := range files {
+		check, err := CheckPlaylist(c, filepath.Join(c.Paths.MPDPlaylistDirectory, file))
+		if err != nil {
+			return problems, err
+		}
+		problems += len(check.Problems)
+		checks = append(checks, check)
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			return problems, err
+		}
+		fmt.Println(string(data))
+		return problems, nil
+	}
+	for _, check := range checks {
+		if len(check.Problems) == 0 {
+			fmt.Print(check.String())
+		} else {
+			fmt.Print(chalk.Red.Color(check.String()))
+		}
+	}
+	fmt.Printf("\n### Found %d problems in %d playlists.\n", problems, len(checks))
+	return
+}
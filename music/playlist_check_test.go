@@ -0,0 +1,61 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+func TestCheckPlaylist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rc := config.Config{Paths: config.Paths{Root: dir, MPDPlaylistDirectory: dir}}
+	album := filepath.Join(dir, "genre", "a", "a (2000) one")
+	if err := os.MkdirAll(album, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(album, "01.flac"), []byte{}, 0777); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "check.m3u")
+	content := "genre/a/a (2000) one/01.flac\n" +
+		"genre/a/a (2000) one/02.flac\n" +
+		filepath.Join(album, "01.flac") + "\n" +
+		"../elsewhere/01.flac\n" +
+		"..Interlude/01.flac\n" +
+		"genre/a/a (2000) one/01.flac\n" +
+		"genre/a/a (2000) \xe9t\xe9/01.flac\n" +
+		"http://radio.example.com/stream\n"
+	if err := ioutil.WriteFile(filename, []byte(content), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	check, err := CheckPlaylist(rc, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PlaylistProblem{
+		{"genre/a/a (2000) one/02.flac", problemMissing},
+		{filepath.Join(album, "01.flac"), problemAbsolute},
+		{"../elsewhere/01.flac", problemOutsideRoot},
+		{"..Interlude/01.flac", problemMissing},
+		{"genre/a/a (2000) one/01.flac", problemDuplicate},
+		{"genre/a/a (2000) \xe9t\xe9/01.flac", problemInvalidUTF8},
+		{"genre/a/a (2000) \xe9t\xe9/01.flac", problemMissing},
+	}
+	if check.Entries != 8 || len(check.Problems) != len(expected) {
+		t.Fatalf("CheckPlaylist(%s) returned %s", filename, check.String())
+	}
+	for i, p := range expected {
+		if check.Problems[i] != p {
+			t.Errorf("CheckPlaylist(%s) returned %v, expected %v", filename, check.Problems[i], p)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package music
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Supported playlist formats.
+const (
+	m3uFormat  = "m3u"
+	plsFormat  = "pls"
+	xspfFormat = "xspf"
+)
+
+// playlistFormat returns the format of a playlist from its extension.
+func playlistFormat(filename string) (format string, err error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".m3u", ".m3u8":
+		return m3uFormat, nil
+	case ".pls":
+		return plsFormat, nil
+	case ".xspf":
+		return xspfFormat, nil
+	}
+	return "", errors.New("Unsupported playlist format: " + filename)
+}
+
+// playlistEntry is a file listed in a playlist, whatever its format.
+type playlistEntry struct {
+	Path     string
+	Duration int // in seconds, -1 if unknown
+	Artist   string
+	Title    string
+	Album    string
+}
+
+// newPlaylistEntry describes a music file, with information from its tags if required.
+// If the file cannot be read, what a playlist already knew about it is kept.
+func newPlaylistEntry(path, file string, withInfo bool, known *playlistEntry) (e playlistEntry) {
+	e = playlistEntry{Path: path, Duration: -1}
+	if !withInfo {
+		return
+	}
+	info, err := ReadTrackInfo(file)
+	if err != nil && known != nil {
+		e.Duration, e.Artist, e.Title, e.Album = known.Duration, known.Artist, known.Title, known.Album
+	} else {
+		if err == nil && info.Duration != 0 {
+			e.Duration = int(info.Duration.Seconds() + 0.5)
+		}
+		e.Artist, e.Title, e.Album = info.Artist, info.Title, info.Album
+	}
+	if e.Title == "" {
+		e.Title = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	return
+}
+
+// displayTitle returns "Artist - Title", as used in m3u and pls playlists.
+func (e *playlistEntry) displayTitle() string {
+	if e.Artist != "" {
+		return e.Artist + " - " + e.Title
+	}
+	return e.Title
+}
+
+// setDisplayTitle parses "Artist - Title", as written in m3u and pls playlists.
+func (e *playlistEntry) setDisplayTitle(title string) {
+	if parts := strings.SplitN(title, " - ", 2); len(parts) == 2 {
+		e.Artist, e.Title = parts[0], parts[1]
+	} else {
+		e.Title = title
+	}
+}
+
+// readM3U parses a m3u playlist, with the durations and titles of #EXTINF directives.
+func readM3U(data []byte) (entries []playlistEntry, extended bool, err error) {
+	lines := strings.Split(strings.TrimPrefix(string(data), "\uFEFF"), "\n")
+	e := playlistEntry{Duration: -1}
+	for i, l := range lines {
+		l = strings.TrimRight(l, "\r")
+		if i == 0 && l == "#EXTM3U" {
+			extended = true
+		}
+		if strings.HasPrefix(l, "#EXTINF:") {
+			// #EXTINF:duration,Artist - Title
+			parts := strings.SplitN(strings.TrimPrefix(l, "#EXTINF:"), ",", 2)
+			if duration, err := strconv.Atoi(strings.TrimSpace(parts[0])); err == nil {
+				e.Duration = duration
+			}
+			if len(parts) == 2 {
+				e.setDisplayTitle(parts[1])
+			}
+		}
+		// ignore other directives and comments
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		e.Path = l
+		entries = append(entries, e)
+		e = playlistEntry{Duration: -1}
+	}
+	return
+}
+
+// writeM3U creates a m3u playlist, with #EXTINF directives if extended.
+func writeM3U(entries []playlistEntry, extended bool) []byte {
+	var b bytes.Buffer
+	if extended {
+		b.WriteString("#EXTM3U\n")
+	}
+	for _, e := range entries {
+		if extended {
+			b.WriteString(fmt.Sprintf("#EXTINF:%d,%s\n", e.Duration, e.displayTitle()))
+		}
+		b.WriteString(e.Path + "\n")
+	}
+	return b.Bytes()
+}
+
+// readPLS parses a pls playlist.
+func readPLS(data []byte) (entries []playlistEntry, err error) {
+	files := map[int]*playlistEntry{}
+	maxIndex := 0
+	for _, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimSpace(l)
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		var field string
+		for _, f := range []string{"file", "title", "length"} {
+			if strings.HasPrefix(key, f) {
+				field = f
+				break
+			}
+		}
+		if field == "" {
+			// [playlist], NumberOfEntries, Version
+			continue
+		}
+		index, err := strconv.Atoi(key[len(field):])
+		if err != nil {
+			continue
+		}
+		if _, ok := files[index]; !ok {
+			files[index] = &playlistEntry{Duration: -1}
+		}
+		if index > maxIndex {
+			maxIndex = index
+		}
+		switch field {
+		case "file":
+			files[index].Path = parts[1]
+		case "title":
+			files[index].setDisplayTitle(parts[1])
+		case "length":
+			if length, err := strconv.Atoi(parts[1]); err == nil {
+				files[index].Duration = length
+			}
+		}
+	}
+	for i := 1; i <= maxIndex; i++ {
+		if e, ok := files[i]; ok && e.Path != "" {
+			entries = append(entries, *e)
+		}
+	}
+	return
+}
+
+// writePLS creates a pls playlist.
+func writePLS(entries []playlistEntry) []byte {
+	var b bytes.Buffer
+	b.WriteString("[playlist]\n")
+	for i, e := range entries {
+		b.WriteString(fmt.Sprintf("File%d=%s\n", i+1, e.Path))
+		b.WriteString(fmt.Sprintf("Title%d=%s\n", i+1, e.displayTitle()))
+		b.WriteString(fmt.Sprintf("Length%d=%d\n", i+1, e.Duration))
+	}
+	b.WriteString(fmt.Sprintf("NumberOfEntries=%d\nVersion=2\n", len(entries)))
+	return b.Bytes()
+}
+
+// xspfPlaylist is the XML structure of a xspf playlist.
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+// xspfTrack is a track of a xspf playlist. Duration is in milliseconds.
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	Duration int    `xml:"duration,omitempty"`
+}
+
+// windowsDrive matches the beginning of absolute Windows paths.
+var windowsDrive = regexp.MustCompile(`^[A-Za-z]:[/\\]`)
+
+// xspfLocation escapes a path into a xspf location: a relative URI, or a file URI for absolute paths.
+func xspfLocation(path string) string {
+	path = filepath.ToSlash(path)
+	switch {
+	case windowsDrive.MatchString(path):
+		return (&url.URL{Scheme: "file", Path: "/" + strings.Replace(path, `\`, "/", -1)}).String()
+	case strings.HasPrefix(path, "/"):
+		return (&url.URL{Scheme: "file", Path: path}).String()
+	}
+	return (&url.URL{Path: path}).String()
+}
+
+// readXSPF parses a xspf playlist.
+func readXSPF(data []byte) (entries []playlistEntry, err error) {
+	var playlist xspfPlaylist
+	if err = xml.Unmarshal(data, &playlist); err != nil {
+		return
+	}
+	for _, t := range playlist.Tracks {
+		location, err := url.Parse(strings.TrimSpace(t.Location))
+		if err != nil {
+			return nil, err
+		}
+		e := playlistEntry{Path: filepath.FromSlash(location.Path), Duration: -1, Artist: t.Creator, Title: t.Title, Album: t.Album}
+		if t.Duration != 0 {
+			e.Duration = t.Duration / 1000
+		}
+		entries = append(entries, e)
+	}
+	return
+}
+
+// writeXSPF creates a xspf playlist.
+func writeXSPF(entries []playlistEntry) (data []byte, err error) {
+	playlist := xspfPlaylist{Version: "1"}
+	for _, e := range entries {
+		t := xspfTrack{Location: xspfLocation(e.Path), Title: e.Title, Creator: e.Artist, Album: e.Album}
+		if e.Duration > 0 {
+			t.Duration = e.Duration * 1000
+		}
+		playlist.Tracks = append(playlist.Tracks, t)
+	}
+	data, err = xml.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	return
+}
+
+// readPlaylistEntries parses playlist data according to its format.
+func readPlaylistEntries(format string, data []byte) (entries []playlistEntry, extended bool, err error) {
+	switch format {
+	case m3uFormat:
+		return readM3U(data)
+	case plsFormat:
+		entries, err = readPLS(data)
+	case xspfFormat:
+		entries, err = readXSPF(data)
+	}
+	return
+}
+
+// writePlaylistEntries creates playlist data according to its format.
+func writePlaylistEntries(format string, entries []playlistEntry, extended bool) (data []byte, err error) {
+	switch format {
+	case m3uFormat:
+		data = writeM3U(entries, extended)
+	case plsFormat:
+		data = writePLS(entries)
+	case xspfFormat:
+		data, err = writeXSPF(entries)
+	}
+	return
+}
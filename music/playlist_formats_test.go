@@ -0,0 +1,86 @@
+package music
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testEntries = []playlistEntry{
+	{Path: "genre/artist/artist (2000) title/01 - one.flac", Duration: 90, Artist: "Artist", Title: "One", Album: "Title"},
+	{Path: "genre/artist/artist (2000) title/02 - two & a half?.flac", Duration: 185, Artist: "Artist", Title: "Two & a half?", Album: "Title"},
+}
+
+var testPlaylistFormats = []struct {
+	filename string
+	format   string
+}{
+	{"a.m3u", m3uFormat},
+	{"a.M3U8", m3uFormat},
+	{"a.pls", plsFormat},
+	{"a.xspf", xspfFormat},
+	{"a.txt", ""},
+}
+
+func TestPlaylistFormat(t *testing.T) {
+	for _, tf := range testPlaylistFormats {
+		format, err := playlistFormat(tf.filename)
+		if format != tf.format || (err != nil) != (tf.format == "") {
+			t.Errorf("playlistFormat(%s) returned %s, %v, expected %s", tf.filename, format, err, tf.format)
+		}
+	}
+}
+
+func TestPLS(t *testing.T) {
+	data, err := writePlaylistEntries(plsFormat, testEntries, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Title2=Artist - Two & a half?\nLength2=185\n") {
+		t.Errorf("writePLS returned %s", string(data))
+	}
+	entries, _, err := readPlaylistEntries(plsFormat, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Path != testEntries[1].Path || entries[1].Duration != 185 {
+		t.Errorf("readPLS returned %+v", entries)
+	}
+}
+
+func TestXSPF(t *testing.T) {
+	data, err := writePlaylistEntries(xspfFormat, testEntries, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<location>genre/artist/artist%20%282000%29%20title/01%20-%20one.flac</location>") {
+		t.Errorf("writeXSPF returned %s", string(data))
+	}
+	if !strings.Contains(string(data), "<duration>90000</duration>") {
+		t.Errorf("writeXSPF returned %s", string(data))
+	}
+	entries, _, err := readPlaylistEntries(xspfFormat, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, testEntries) {
+		t.Errorf("readXSPF returned %+v, expected %+v", entries, testEntries)
+	}
+}
+
+func TestM3U(t *testing.T) {
+	data, err := writePlaylistEntries(m3uFormat, testEntries, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "#EXTM3U\n#EXTINF:90,Artist - One\ngenre/") {
+		t.Errorf("writeM3U returned %s", string(data))
+	}
+	entries, extended, err := readPlaylistEntries(m3uFormat, data)
+	if err != nil || !extended || len(entries) != 2 || entries[0].Path != testEntries[0].Path {
+		t.Errorf("readM3U returned %+v, %v, %v", entries, extended, err)
+	}
+	if e := entries[1]; e.Duration != 185 || e.Artist != "Artist" || e.Title != "Two & a half?" {
+		t.Errorf("readM3U returned %+v for #EXTINF:185,Artist - Two & a half?", e)
+	}
+}
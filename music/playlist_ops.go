@@ -0,0 +1,137 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/ttacon/chalk"
+)
+
+// Set operations on playlists.
+const (
+	OperationMerge     = "merge"
+	OperationIntersect = "intersect"
+	OperationSubtract  = "subtract"
+	OperationDiff      = "diff"
+)
+
+// trackKey identifies a Track in set operations, at album or track granularity.
+func trackKey(t Track, albumLevel bool) string {
+	if albumLevel {
+		return filepath.Clean(t.Album.Path)
+	}
+	return filepath.Clean(t.String())
+}
+
+// tracks returns the contents of a playlist without duplicates, in order.
+// At album granularity, tracks are replaced by their whole album.
+func (p *Playlist) tracks(albumLevel bool) (tracks []Track) {
+	seen := make(map[string]bool)
+	for _, t := range p.contents {
+		if albumLevel {
+			t.Filename = ""
+		}
+		if key := trackKey(t, albumLevel); !seen[key] {
+			seen[key] = true
+			tracks = append(tracks, t)
+		}
+	}
+	return
+}
+
+// keys returns the set of tracks of a playlist.
+func (p *Playlist) keys(albumLevel bool) map[string]bool {
+	keys := make(map[string]bool)
+	for _, t := range p.contents {
+		keys[trackKey(t, albumLevel)] = true
+	}
+	return keys
+}
+
+// filter returns the tracks of a playlist that are, or are not, in another one.
+func (p *Playlist) filter(other Playlist, albumLevel, inOther bool) (tracks []Track) {
+	keys := other.keys(albumLevel)
+	for _, t := range p.tracks(albumLevel) {
+		if keys[trackKey(t, albumLevel)] == inOther {
+			tracks = append(tracks, t)
+		}
+	}
+	return
+}
+
+// Merge returns the tracks of all playlists, in order, without duplicates.
+func Merge(albumLevel bool, playlists ...Playlist) (merged Playlist) {
+	merged.AlbumLevel = albumLevel
+	for _, p := range playlists {
+		merged.contents = append(merged.contents, p.contents...)
+	}
+	merged.contents = merged.tracks(albumLevel)
+	return
+}
+
+// Intersect returns the tracks of a playlist that are also in another one, in order.
+func Intersect(albumLevel bool, p, other Playlist) Playlist {
+	return Playlist{AlbumLevel: albumLevel, contents: p.filter(other, albumLevel, true)}
+}
+
+// Subtract returns the tracks of a playlist that are not in another one, in order.
+func Subtract(albumLevel bool, p, other Playlist) Playlist {
+	return Playlist{AlbumLevel: albumLevel, contents: p.filter(other, albumLevel, false)}
+}
+
+// Diff returns the tracks that are only in the first playlist, and those only in the second.
+func Diff(albumLevel bool, p, other Playlist) (onlyFirst, onlySecond Playlist) {
+	return Subtract(albumLevel, p, other), Subtract(albumLevel, other, p)
+}
+
+// CombinePlaylists applies a set operation to playlists of MPDPlaylistDirectory.
+// The result is written to output, except for diff, which is printed.
+func CombinePlaylists(c config.Config, operation string, names []string, output string, albumLevel bool) (err error) {
+	if len(names) < 2 || (operation != OperationMerge && len(names) != 2) {
+		return errors.New("Usage: radis playlist " + operation + " <playlist> <playlist> -o <output>")
+	}
+	if operation != OperationDiff && output == "" {
+		return errors.New("An output playlist is required.")
+	}
+	playlists := []Playlist{}
+	for _, name := range names {
+		p := Playlist{Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, name)}
+		if isPlaylist, err := p.Exists(); err != nil || !isPlaylist {
+			return errors.New(name + " is not a playlist.")
+		}
+		if err = p.Load(c.Paths.Root); err != nil {
+			return
+		}
+		playlists = append(playlists, p)
+	}
+
+	var result Playlist
+	switch operation {
+	case OperationMerge:
+		result = Merge(albumLevel, playlists...)
+	case OperationIntersect:
+		result = Intersect(albumLevel, playlists[0], playlists[1])
+	case OperationSubtract:
+		result = Subtract(albumLevel, playlists[0], playlists[1])
+	case OperationDiff:
+		onlyFirst, onlySecond := Diff(albumLevel, playlists[0], playlists[1])
+		for _, t := range onlyFirst.contents {
+			fmt.Println(chalk.Red.Color("- " + t.String()))
+		}
+		for _, t := range onlySecond.contents {
+			fmt.Println(chalk.Green.Color("+ " + t.String()))
+		}
+		fmt.Printf("\n### %d only in %s, %d only in %s.\n", len(onlyFirst.contents), names[0], len(onlySecond.contents), names[1])
+		return
+	default:
+		return errors.New("Unknown playlist operation " + operation)
+	}
+	result.Filename = filepath.Join(c.Paths.MPDPlaylistDirectory, output)
+	if err = result.Write(); err != nil {
+		return
+	}
+	fmt.Println("Wrote " + result.String())
+	return
+}
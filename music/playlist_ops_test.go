@@ -0,0 +1,43 @@
+package music
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testTrackList(p Playlist) (tracks []string) {
+	for _, t := range p.contents {
+		tracks = append(tracks, t.String())
+	}
+	return
+}
+
+func TestSetOperations(t *testing.T) {
+	one := Album{Path: "genre/a/a (2000) one"}
+	two := Album{Path: "genre/a/a (2001) two"}
+	three := Album{Path: "genre/a/a (2002) three"}
+	pa := Playlist{contents: []Track{{Album: one, Filename: "01.flac"}, {Album: two, Filename: "01.flac"}, {Album: one, Filename: "01.flac"}}}
+	pb := Playlist{contents: []Track{{Album: three, Filename: "01.flac"}, {Album: one, Filename: "02.flac"}, {Album: two, Filename: "01.flac"}}}
+
+	tests := []struct {
+		name     string
+		result   Playlist
+		expected []string
+	}{
+		{"merge", Merge(false, pa, pb), []string{"genre/a/a (2000) one/01.flac", "genre/a/a (2001) two/01.flac", "genre/a/a (2002) three/01.flac", "genre/a/a (2000) one/02.flac"}},
+		{"merge albums", Merge(true, pa, pb), []string{"genre/a/a (2000) one", "genre/a/a (2001) two", "genre/a/a (2002) three"}},
+		{"intersect", Intersect(false, pa, pb), []string{"genre/a/a (2001) two/01.flac"}},
+		{"intersect albums", Intersect(true, pa, pb), []string{"genre/a/a (2000) one", "genre/a/a (2001) two"}},
+		{"subtract", Subtract(false, pa, pb), []string{"genre/a/a (2000) one/01.flac"}},
+		{"subtract albums", Subtract(true, pb, pa), []string{"genre/a/a (2002) three"}},
+	}
+	for _, tt := range tests {
+		if v := testTrackList(tt.result); !reflect.DeepEqual(v, tt.expected) {
+			t.Errorf("%s returned %v, expected %v", tt.name, v, tt.expected)
+		}
+	}
+	onlyFirst, onlySecond := Diff(false, pa, pb)
+	if len(onlyFirst.contents) != 1 || len(onlySecond.contents) != 2 {
+		t.Errorf("diff returned %v and %v", testTrackList(onlyFirst), testTrackList(onlySecond))
+	}
+}
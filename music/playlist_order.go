@@ -0,0 +1,118 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+// albumGroup holds the tracks of an album found in a playlist, in order.
+type albumGroup struct {
+	key    string
+	tracks []Track
+}
+
+// albumGroups gathers the tracks of a playlist by album, in order of first appearance.
+func (p *Playlist) albumGroups() (groups []albumGroup) {
+	index := make(map[string]int)
+	for _, t := range p.contents {
+		key := trackKey(t, true)
+		if i, ok := index[key]; ok {
+			groups[i].tracks = append(groups[i].tracks, t)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, albumGroup{tracks: []Track{t}})
+	}
+	return
+}
+
+// orderKey returns what an album is compared on to order a playlist.
+func orderKey(c config.Config, a Album, by string) string {
+	// a is a copy, finding its genre does not change where the playlist points to
+	location := a.NewPath
+	if location == "" {
+		location = a.Path
+	}
+	if !a.IsValidAlbum() {
+		return ""
+	}
+	if _, err := a.FindNewPath(c); err != nil {
+		return ""
+	}
+	switch by {
+	case config.OrderYear:
+		return a.year
+	case config.OrderArtist:
+		return strings.ToLower(a.mainAlias)
+	case config.OrderGenre:
+		return strings.ToLower(a.genre)
+	case config.OrderAdded:
+		fileInfo, err := os.Stat(location)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%020d", fileInfo.ModTime().UnixNano())
+	}
+	return ""
+}
+
+// Order sorts or shuffles the albums of a playlist, keeping the tracks of each album together and in order.
+// The same seed always gives the same shuffle.
+func (p *Playlist) Order(c config.Config, by string, reverse bool, seed int64) (err error) {
+	if !config.IsValidOrder(by) {
+		return errors.New("Unknown order " + by + ", use one of: " + strings.Join(config.Orders, ", "))
+	}
+	groups := p.albumGroups()
+	if by == config.OrderShuffle {
+		shuffled := make([]albumGroup, len(groups))
+		for i, j := range rand.New(rand.NewSource(seed)).Perm(len(groups)) {
+			shuffled[i] = groups[j]
+		}
+		groups = shuffled
+	} else {
+		for i := range groups {
+			groups[i].key = orderKey(c, groups[i].tracks[0].Album, by)
+		}
+		sort.SliceStable(groups, func(i, j int) bool {
+			return groups[i].key < groups[j].key
+		})
+	}
+	if reverse {
+		for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+			groups[i], groups[j] = groups[j], groups[i]
+		}
+	}
+	p.contents = []Track{}
+	for _, g := range groups {
+		p.contents = append(p.contents, g.tracks...)
+	}
+	return
+}
+
+// OrderPlaylist sorts or shuffles a playlist of MPDPlaylistDirectory.
+// A random seed is used, and displayed, if none is given.
+func OrderPlaylist(c config.Config, filename, by string, reverse bool, seed int64) (err error) {
+	p := Playlist{Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, filename)}
+	if isPlaylist, err := p.Exists(); err != nil || !isPlaylist {
+		return errors.New(filename + " is not a playlist.")
+	}
+	if err = p.Load(c.Paths.Root); err != nil {
+		return
+	}
+	if by == config.OrderShuffle && seed == 0 {
+		seed = time.Now().UnixNano()
+		fmt.Printf("Shuffling with seed %d.\n", seed)
+	}
+	if err = p.Order(c, by, reverse, seed); err != nil {
+		return
+	}
+	return p.Write()
+}
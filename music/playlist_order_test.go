@@ -0,0 +1,70 @@
+package music
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+func TestOrder(t *testing.T) {
+	oc := config.Config{Paths: config.Paths{Root: "/music", UnsortedSubdir: "UNCATEGORIZED"}}
+	b := Album{Root: "/music", Path: "UNCATEGORIZED/b/b (1990) one"}
+	a := Album{Root: "/music", Path: "UNCATEGORIZED/a/a (2001) two"}
+	c := Album{Root: "/music", Path: "UNCATEGORIZED/c/c (1970) three"}
+	contents := []Track{
+		{Album: b, Filename: "01.flac"}, {Album: a, Filename: "02.flac"}, {Album: c, Filename: "01.flac"},
+		{Album: a, Filename: "01.flac"}, {Album: b, Filename: "02.flac"},
+	}
+
+	tests := []struct {
+		by       string
+		reverse  bool
+		expected []string
+	}{
+		{config.OrderYear, false, []string{
+			"UNCATEGORIZED/c/c (1970) three/01.flac",
+			"UNCATEGORIZED/b/b (1990) one/01.flac", "UNCATEGORIZED/b/b (1990) one/02.flac",
+			"UNCATEGORIZED/a/a (2001) two/02.flac", "UNCATEGORIZED/a/a (2001) two/01.flac",
+		}},
+		{config.OrderArtist, true, []string{
+			"UNCATEGORIZED/c/c (1970) three/01.flac",
+			"UNCATEGORIZED/b/b (1990) one/01.flac", "UNCATEGORIZED/b/b (1990) one/02.flac",
+			"UNCATEGORIZED/a/a (2001) two/02.flac", "UNCATEGORIZED/a/a (2001) two/01.flac",
+		}},
+	}
+	for _, tt := range tests {
+		p := Playlist{contents: append([]Track{}, contents...)}
+		if err := p.Order(oc, tt.by, tt.reverse, 0); err != nil {
+			t.Fatal(err)
+		}
+		if v := testTrackList(p); !reflect.DeepEqual(v, tt.expected) {
+			t.Errorf("Order(%s) returned %v, expected %v", tt.by, v, tt.expected)
+		}
+	}
+
+	// shuffles keep albums together and are reproducible
+	p1 := Playlist{contents: append([]Track{}, contents...)}
+	p2 := Playlist{contents: append([]Track{}, contents...)}
+	if err := p1.Order(oc, config.OrderShuffle, false, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := p2.Order(oc, config.OrderShuffle, false, 42); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(testTrackList(p1), testTrackList(p2)) {
+		t.Errorf("Order(shuffle) is not reproducible: %v, %v", testTrackList(p1), testTrackList(p2))
+	}
+	changes := 0
+	for i := 1; i < len(p1.contents); i++ {
+		if p1.contents[i].Album.Path != p1.contents[i-1].Album.Path {
+			changes++
+		}
+	}
+	if changes != 2 {
+		t.Errorf("Order(shuffle) split albums: %v", testTrackList(p1))
+	}
+	if err := p1.Order(oc, "random", false, 0); err == nil {
+		t.Errorf("Order(random) should have failed")
+	}
+}
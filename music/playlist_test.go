@@ -1,17 +1,26 @@
 package music
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
 
 var af1 = Album{Root: ".", Path: "test (2009) doié? [MP3]"}
 var af2 = Album{Root: ".", Path: ".._12Jïâ!! (2012) AA1AQ"}
-var p = Playlist{Filename: "hop.m3u", contents: []Album{af1, af2}}
+var p = Playlist{Filename: "hop.m3u", AlbumLevel: true, contents: []Track{{Album: af1}, {Album: af2}}}
 
 var testPlaylists = []struct {
 	Playlist Playlist
 	expected string
 }{
 	{p, "hop.m3u: 2 albums"},
-	{Playlist{Filename: "éé?.m3u", contents: []Album{af1}}, "éé?.m3u: 1 albums"},
+	{Playlist{Filename: "éé?.m3u", AlbumLevel: true, contents: []Track{{Album: af1}}}, "éé?.m3u: 1 albums"},
+	{Playlist{Filename: "tracks.m3u", contents: []Track{{Album: af1, Filename: "01.flac"}, {Album: af1, Filename: "02.flac"}}}, "tracks.m3u: 2 tracks"},
 }
 
 func TestPlaylistString(t *testing.T) {
@@ -36,9 +45,221 @@ func TestWrite(t *testing.T) {
 }
 
 func TestUpdate(t *testing.T) {
-	// TODO
+	dir, err := ioutil.TempDir("", "radis_playlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pc := config.Config{
+		Paths:  config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED"},
+		Genres: config.Genres{config.Genre{Name: "genre1", Artists: []string{"artist"}}},
+	}
+	album := filepath.Join(dir, "genre1", "artist", "artist (2000) title")
+	if err := os.MkdirAll(album, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"01.flac", "02.flac", "03.flac"} {
+		if err := ioutil.WriteFile(filepath.Join(album, file), []byte{}, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the playlist was written before the album moved
+	filename := filepath.Join(dir, "favourites.m3u")
+	content := "UNCATEGORIZED/artist/artist (2000) title/02.flac\n"
+	if err := ioutil.WriteFile(filename, []byte(content), 0777); err != nil {
+		t.Fatal(err)
+	}
+	pl := Playlist{Filename: filename}
+	if err := pl.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.Update(pc); err != nil {
+		t.Errorf("Update(%s) returned an error: %s", filename, err.Error())
+	}
+	if err := pl.Write(); err != nil {
+		t.Errorf("Write(%s) returned an error: %s", filename, err.Error())
+	}
+	written, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "genre1/artist/artist (2000) title/02.flac\n"
+	if string(written) != expected {
+		t.Errorf("Update(%s) wrote %s, expected %s", filename, string(written), expected)
+	}
 }
 
 func TestLoad(t *testing.T) {
-	// TODO
+	dir, err := ioutil.TempDir("", "radis_playlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "test.m3u")
+	content := "#EXTM3U\r\n" +
+		"#EXTINF:90,Artist - One\r\n" +
+		"genre/artist/artist (2000) title/01.flac\r\n" +
+		"#EXTINF:91,Artist - Two\r\n" +
+		"genre/artist/artist (2000) title/02.flac\r\n" +
+		"genre/artist/artist (2001) image/image.cue/track0001\r\n"
+	if err := ioutil.WriteFile(filename, []byte(content), 0777); err != nil {
+		t.Fatal(err)
+	}
+	pl := Playlist{Filename: filename}
+	if err := pl.Load("/music"); err != nil {
+		t.Errorf("Load(%s) returned an error: %s", filename, err.Error())
+	}
+	if !pl.Extended {
+		t.Errorf("Load(%s) did not detect an extended playlist", filename)
+	}
+	expected := []string{
+		"genre/artist/artist (2000) title/01.flac",
+		"genre/artist/artist (2000) title/02.flac",
+		"genre/artist/artist (2001) image/image.cue/track0001",
+	}
+	if len(pl.contents) != len(expected) {
+		t.Fatalf("Load(%s) returned %d tracks, expected %d", filename, len(pl.contents), len(expected))
+	}
+	for i := range expected {
+		if pl.contents[i].String() != expected[i] {
+			t.Errorf("Load(%s) returned track %s, expected %s", filename, pl.contents[i].String(), expected[i])
+		}
+	}
+
+	// album level
+	pl = Playlist{Filename: filename, AlbumLevel: true}
+	if err := pl.Load("/music"); err != nil {
+		t.Errorf("Load(%s) returned an error: %s", filename, err.Error())
+	}
+	expected = []string{"genre/artist/artist (2000) title", "genre/artist/artist (2001) image"}
+	if len(pl.contents) != len(expected) {
+		t.Fatalf("Load(%s) returned %d albums, expected %d", filename, len(pl.contents), len(expected))
+	}
+	for i := range expected {
+		if pl.contents[i].String() != expected[i] {
+			t.Errorf("Load(%s) returned album %s, expected %s", filename, pl.contents[i].String(), expected[i])
+		}
+	}
+}
+
+func TestUpdateAndSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_playlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pc := config.Config{
+		Paths:  config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: dir},
+		Genres: config.Genres{config.Genre{Name: "genre1", Artists: []string{"artist", "other"}}},
+	}
+	createTestAlbums(t, pc, "genre1/artist/artist (2000) title", "genre1/other/other (2001) title")
+	filename := filepath.Join(dir, "mixed.m3u")
+	content := "UNCATEGORIZED/artist/artist (2000) title/01.flac\n" +
+		"genre1/other/other (2001) title/01.flac\n" +
+		"genre1/gone/gone (1999) title/01.flac\n"
+	if err := ioutil.WriteFile(filename, []byte(content), 0777); err != nil {
+		t.Fatal(err)
+	}
+	pl := Playlist{Filename: filename}
+	report, err := pl.UpdateAndSave(pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != (UpdateReport{Remapped: 1, Unchanged: 1, Unresolvable: 1}) {
+		t.Errorf("UpdateAndSave(%s) returned %s", filename, report.String())
+	}
+	written, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "genre1/artist/artist (2000) title/01.flac\n" +
+		"genre1/other/other (2001) title/01.flac\n" +
+		"genre1/gone/gone (1999) title/01.flac\n"
+	if string(written) != expected {
+		t.Errorf("UpdateAndSave(%s) wrote %s, expected %s", filename, string(written), expected)
+	}
+}
+
+func TestWriteRollingPlaylists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_playlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rc := config.Config{
+		Paths: config.Paths{Root: dir, MPDPlaylistDirectory: dir},
+		Rolling: config.RollingPlaylists{
+			{Period: config.Daily, Filename: "{year}-{month}-{day}.m3u", Keep: 2, Archive: "archive"},
+			{Period: config.Last, Filename: "last.m3u", Count: 2},
+		},
+	}
+	albums := createTestAlbums(t, rc, "genre/a/a (2000) one", "genre/a/a (2001) two", "genre/a/a (2002) three")
+	// an old daily playlist
+	if err := ioutil.WriteFile(filepath.Join(dir, "2000-01-01.m3u"), []byte{}, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	playlists := loadRollingPlaylists(rc)
+	for _, a := range albums {
+		// not synced
+		a.NewPath = a.Path
+		for i := range playlists {
+			playlists[i].AddAlbum(a)
+		}
+	}
+	if err := writeRollingPlaylists(rc, playlists); err != nil {
+		t.Fatal(err)
+	}
+	written, err := ioutil.ReadFile(filepath.Join(dir, "last.m3u"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "genre/a/a (2001) two/01.flac\ngenre/a/a (2002) three/01.flac\n"
+	if string(written) != expected {
+		t.Errorf("writeRollingPlaylists wrote %s, expected %s", string(written), expected)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "2000-01-01.m3u")); err != nil {
+		t.Errorf("writeRollingPlaylists did not archive an expired playlist: %s", err.Error())
+	}
+}
+
+func TestConvertPlaylist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_convert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rc := config.Config{
+		Paths:  config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: dir},
+		Genres: config.Genres{config.Genre{Name: "genre", Artists: []string{"a"}}},
+	}
+	createTestAlbums(t, rc, "genre/a/a (2000) one")
+	// the test files have no tags, the playlist knows better
+	content := "#EXTM3U\n#EXTINF:185,A - One\ngenre/a/a (2000) one/01.flac\n#EXTINF:90,A - Gone\ngenre/a/a (1999) gone/01.flac\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "mix.m3u"), []byte(content), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertPlaylist(rc, "mix.m3u", "mix.xspf"); err != nil {
+		t.Fatal(err)
+	}
+	written, err := ioutil.ReadFile(filepath.Join(dir, "mix.xspf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"<location>genre/a/a%20%282000%29%20one/01.flac</location>\n      <title>One</title>\n      <creator>A</creator>\n      <duration>185000</duration>",
+		"<location>genre/a/a%20%281999%29%20gone/01.flac</location>\n      <title>Gone</title>\n      <creator>A</creator>\n      <duration>90000</duration>",
+	} {
+		if !strings.Contains(string(written), expected) {
+			t.Errorf("ConvertPlaylist wrote:\n%s\nexpected %s", written, expected)
+		}
+	}
+	if err := ConvertPlaylist(rc, "mix.m3u", "mix.txt"); err == nil {
+		t.Errorf("ConvertPlaylist should have returned an error for an unsupported format")
+	}
 }
@@ -0,0 +1,356 @@
+package music
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/ttacon/chalk"
+	"gopkg.in/yaml.v2"
+)
+
+// sessionGap separates two listening sessions of the same album.
+const sessionGap = time.Hour
+
+// AlbumPlays is what is known about how an album was listened to.
+type AlbumPlays struct {
+	// Stickers is the play count of the album, from the playcount stickers of its songs.
+	Stickers int `yaml:"stickers,omitempty"`
+	// Rating is the average rating of its songs, out of 10.
+	Rating int `yaml:"rating,omitempty"`
+	// History lists when its tracks were played, in order.
+	History []time.Time `yaml:"history,omitempty"`
+}
+
+// Count returns how many times the album was played.
+// Tracks played less than sessionGap apart count as one play of the album.
+func (ap *AlbumPlays) Count() int {
+	sessions := 0
+	for i, t := range ap.History {
+		if i == 0 || t.Sub(ap.History[i-1]) > sessionGap {
+			sessions++
+		}
+	}
+	if ap.Stickers > sessions {
+		return ap.Stickers
+	}
+	return sessions
+}
+
+// LastPlayed returns when the album was last played, or zero if it never was.
+func (ap *AlbumPlays) LastPlayed() time.Time {
+	if len(ap.History) == 0 {
+		return time.Time{}
+	}
+	return ap.History[len(ap.History)-1]
+}
+
+// addPlay records that a track was played, unless it is already known.
+func (ap *AlbumPlays) addPlay(at time.Time) bool {
+	i := sort.Search(len(ap.History), func(i int) bool { return !ap.History[i].Before(at) })
+	if i < len(ap.History) && ap.History[i].Equal(at) {
+		return false
+	}
+	ap.History = append(ap.History, time.Time{})
+	copy(ap.History[i+1:], ap.History[i:])
+	ap.History[i] = at
+	return true
+}
+
+// PlayIndex holds the play statistics of albums, by album name, so that they survive albums moving around.
+type PlayIndex map[string]*AlbumPlays
+
+// playKey returns the name under which an album is found in the PlayIndex.
+func playKey(a Album) string {
+	if !a.IsValidAlbum() {
+		return ""
+	}
+	return a.artist + " (" + a.year + ") " + a.title
+}
+
+// LoadPlayIndex reads the play statistics; a missing or empty file gives an empty index.
+func LoadPlayIndex(path string) (index PlayIndex, err error) {
+	index = make(PlayIndex)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return
+	}
+	err = yaml.Unmarshal(data, &index)
+	return
+}
+
+// Save the play statistics, without the albums radis knows nothing about.
+func (pi PlayIndex) Save(path string) (err error) {
+	for key, ap := range pi {
+		if ap.Stickers == 0 && ap.Rating == 0 && len(ap.History) == 0 {
+			delete(pi, key)
+		}
+	}
+	data, err := yaml.Marshal(pi)
+	if err != nil {
+		return
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Of returns the play statistics of an album.
+func (pi PlayIndex) Of(a Album) AlbumPlays {
+	if ap, ok := pi[playKey(a)]; ok {
+		return *ap
+	}
+	return AlbumPlays{}
+}
+
+// get returns the play statistics of an album name, adding it to the index if necessary.
+func (pi PlayIndex) get(key string) *AlbumPlays {
+	if _, ok := pi[key]; !ok {
+		pi[key] = &AlbumPlays{}
+	}
+	return pi[key]
+}
+
+// albumOfURI returns the album of a song, as MPD names it relative to the music directory.
+func albumOfURI(root, uri string) (a Album, ok bool) {
+	if strings.Contains(uri, "://") {
+		return
+	}
+	path := filepath.Dir(filepath.Join(root, uri))
+	// tracks of CUE sheets are album.cue/track0001
+	if strings.ToLower(filepath.Ext(path)) == ".cue" {
+		path = filepath.Dir(path)
+	}
+	a = Album{Root: root, Path: path}
+	return a, a.IsValidAlbum()
+}
+
+// setStickers replaces the play counts and ratings of albums with the stickers of their songs, by MPD URI.
+// The play count of an album is the average play count of its songs with a sticker.
+func (pi PlayIndex) setStickers(root string, playCounts, ratings map[string]string) (albums int) {
+	for _, ap := range pi {
+		ap.Stickers, ap.Rating = 0, 0
+	}
+	average := func(values map[string]string, set func(ap *AlbumPlays, value int)) {
+		sums := make(map[string]int)
+		counts := make(map[string]int)
+		for uri, value := range values {
+			v, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				continue
+			}
+			a, ok := albumOfURI(root, uri)
+			if !ok {
+				continue
+			}
+			sums[playKey(a)] += v
+			counts[playKey(a)]++
+		}
+		for key, sum := range sums {
+			set(pi.get(key), int(math.Floor(float64(sum)/float64(counts[key])+0.5)))
+		}
+	}
+	updated := make(map[*AlbumPlays]bool)
+	average(playCounts, func(ap *AlbumPlays, value int) {
+		ap.Stickers = value
+		updated[ap] = true
+	})
+	average(ratings, func(ap *AlbumPlays, value int) {
+		ap.Rating = value
+		updated[ap] = true
+	})
+	return len(updated)
+}
+
+// play is a song played by MPD.
+type play struct {
+	uri string
+	at  time.Time
+}
+
+// mpdLogPattern matches the lines of mpd.log about played songs, capturing the timestamp and the URI.
+var mpdLogPattern = regexp.MustCompile(`^(.*?)\s*:?\s+player: played "(.*)"$`)
+
+// mpdLogLayouts are the timestamps used by the different versions of MPD.
+var mpdLogLayouts = []string{"2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05", "Jan _2 15:04:05", "Jan _2 15:04"}
+
+// parseMPDLogTime parses the timestamp of a line of mpd.log.
+// Old versions of MPD do not log the year, the last occurrence of the date before now is used.
+func parseMPDLogTime(timestamp string, now time.Time) (at time.Time, err error) {
+	for _, layout := range mpdLogLayouts {
+		if at, err = time.ParseInLocation(layout, timestamp, now.Location()); err != nil {
+			continue
+		}
+		if at.Year() == 0 {
+			at = at.AddDate(now.Year(), 0, 0)
+			if at.After(now) {
+				at = at.AddDate(-1, 0, 0)
+			}
+		}
+		return at, nil
+	}
+	return at, errors.New("Unknown timestamp in mpd.log: " + timestamp)
+}
+
+// parseMPDLog returns the songs played according to mpd.log, ignoring lines it does not understand.
+func parseMPDLog(r io.Reader, now time.Time) (plays []play, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		matches := mpdLogPattern.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+		at, err := parseMPDLogTime(matches[1], now)
+		if err != nil {
+			continue
+		}
+		plays = append(plays, play{uri: matches[2], at: at})
+	}
+	return plays, scanner.Err()
+}
+
+// addPlays adds played songs to the history of their albums, and returns how many were not known yet.
+func (pi PlayIndex) addPlays(root string, plays []play) (added int) {
+	for _, p := range plays {
+		a, ok := albumOfURI(root, p.uri)
+		if !ok {
+			continue
+		}
+		if pi.get(playKey(a)).addPlay(p.at) {
+			added++
+		}
+	}
+	return
+}
+
+// UpdatePlays reads the MPD stickers and the mpd.log set in radis.yaml, and updates the play statistics.
+func UpdatePlays(c config.Config) (err error) {
+	defer timeTrack(time.Now(), "Updating play statistics")
+
+	if !c.MPD.IsConfigured() && c.MPD.LogFile == "" {
+		return errors.New("MPD or its LogFile must be configured in radis.yaml.")
+	}
+	path, err := c.PlayIndexFile()
+	if err != nil {
+		return
+	}
+	index, err := LoadPlayIndex(path)
+	if err != nil {
+		return
+	}
+	if c.MPD.IsConfigured() {
+		client, err := connectToMPD(c)
+		if err != nil {
+			return err
+		}
+		playCounts, err := client.Stickers(c.MPD.PlayCountSticker)
+		if err == nil {
+			var ratings map[string]string
+			if ratings, err = client.Stickers(c.MPD.RatingSticker); err == nil {
+				fmt.Printf("Read the stickers of %d albums.\n", index.setStickers(c.Paths.Root, playCounts, ratings))
+			}
+		}
+		client.Close()
+		if err != nil {
+			// the sticker database is optional
+			fmt.Println(chalk.Yellow.Color("Could not read MPD stickers: " + err.Error()))
+		}
+	}
+	if c.MPD.LogFile != "" {
+		f, err := os.Open(c.MPD.LogFile)
+		if err != nil {
+			return err
+		}
+		plays, err := parseMPDLog(f, time.Now())
+		f.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Found %d new plays in %s.\n", index.addPlays(c.Paths.Root, plays), c.MPD.LogFile)
+	}
+	if err = index.Save(path); err != nil {
+		return
+	}
+	fmt.Printf("\n### Play statistics of %d albums saved in %s.\n", len(index), path)
+	return
+}
+
+// albumPlays is an album of the collection, with its play statistics.
+type albumPlays struct {
+	Album
+	AlbumPlays
+}
+
+// printAlbumPlays lists up to limit albums with their play statistics.
+func printAlbumPlays(title string, albums []albumPlays, limit int) {
+	fmt.Printf("%s%s (%d):\n%s", chalk.Blue, title, len(albums), chalk.Reset)
+	for i, a := range albums {
+		if i == limit {
+			fmt.Printf("\t... and %d more.\n", len(albums)-limit)
+			break
+		}
+		txt := fmt.Sprintf("\t%3d plays", a.Count())
+		if last := a.LastPlayed(); !last.IsZero() {
+			txt += ", last on " + last.Format("2006-01-02")
+		}
+		if a.Rating != 0 {
+			txt += fmt.Sprintf(", rated %d/10", a.Rating)
+		}
+		fmt.Println(txt + "\t" + a.Album.String())
+	}
+	fmt.Println()
+}
+
+// ShowPlayStats lists the most played, recently played, and never played albums of the collection.
+func ShowPlayStats(c config.Config, limit int, recent time.Duration) (err error) {
+	defer timeTrack(time.Now(), "Scanning files")
+
+	path, err := c.PlayIndexFile()
+	if err != nil {
+		return
+	}
+	index, err := LoadPlayIndex(path)
+	if err != nil {
+		return
+	}
+	albums, err := getAlbums(c)
+	if err != nil {
+		return
+	}
+	played, recentlyPlayed, neverPlayed := []albumPlays{}, []albumPlays{}, []albumPlays{}
+	for _, a := range albums {
+		ap := albumPlays{a, index.Of(a)}
+		switch {
+		case ap.Count() == 0:
+			neverPlayed = append(neverPlayed, ap)
+		case !ap.LastPlayed().IsZero() && time.Since(ap.LastPlayed()) <= recent:
+			recentlyPlayed = append(recentlyPlayed, ap)
+			fallthrough
+		default:
+			played = append(played, ap)
+		}
+	}
+	sort.SliceStable(played, func(i, j int) bool { return played[i].Count() > played[j].Count() })
+	sort.SliceStable(recentlyPlayed, func(i, j int) bool {
+		return recentlyPlayed[i].LastPlayed().After(recentlyPlayed[j].LastPlayed())
+	})
+
+	printAlbumPlays("Most played albums", played, limit)
+	printAlbumPlays("Recently played albums", recentlyPlayed, limit)
+	printAlbumPlays("Never played albums", neverPlayed, limit)
+	fmt.Printf("### %d albums, %d played, %d never played.\n", len(albums), len(played), len(neverPlayed))
+	return
+}
@@ -0,0 +1,152 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+var testNow = time.Date(2016, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+var testMPDLogTimes = []struct {
+	timestamp   string
+	expected    time.Time
+	expectedErr bool
+}{
+	{"2016-02-27T21:04:05", time.Date(2016, time.February, 27, 21, 4, 5, 0, time.UTC), false},
+	{"2016-02-27T21:04:05+01:00", time.Date(2016, time.February, 27, 20, 4, 5, 0, time.UTC), false},
+	{"Feb 27 21:04", time.Date(2016, time.February, 27, 21, 4, 0, 0, time.UTC), false},
+	{"Feb  7 21:04:05", time.Date(2016, time.February, 7, 21, 4, 5, 0, time.UTC), false},
+	// a date after now was last year
+	{"Dec 31 23:59", time.Date(2015, time.December, 31, 23, 59, 0, 0, time.UTC), false},
+	{"yesterday", time.Time{}, true},
+}
+
+func TestParseMPDLogTime(t *testing.T) {
+	for _, tt := range testMPDLogTimes {
+		at, err := parseMPDLogTime(tt.timestamp, testNow)
+		if (err != nil) != tt.expectedErr || (err == nil && !at.Equal(tt.expected)) {
+			t.Errorf("parseMPDLogTime(%s) returned %s, %v, expected %s", tt.timestamp, at, err, tt.expected)
+		}
+	}
+}
+
+func TestParseMPDLog(t *testing.T) {
+	log := `Feb 27 21:00 : player: played "Jazz/Miles/Miles (1959) Blue/01.flac"
+Feb 27 21:05 : update: added Jazz/Miles/Miles (1959) Blue/02.flac
+2016-02-28T10:00:00 player: played "Rock/Band/Band (1960) "Loud"/album.cue/track0002"
+2016-02-28T10:05:00 player: played "http://radio.example.com/stream"
+`
+	plays, err := parseMPDLog(strings.NewReader(log), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plays) != 3 {
+		t.Fatalf("parseMPDLog returned %d plays, expected 3", len(plays))
+	}
+	if plays[1].uri != `Rock/Band/Band (1960) "Loud"/album.cue/track0002` {
+		t.Errorf("parseMPDLog returned %s", plays[1].uri)
+	}
+
+	index := make(PlayIndex)
+	if added := index.addPlays("/music", plays); added != 2 {
+		t.Errorf("addPlays added %d plays, expected 2", added)
+	}
+	// reading the same log again adds nothing
+	if added := index.addPlays("/music", plays); added != 0 {
+		t.Errorf("addPlays added %d plays again, expected 0", added)
+	}
+	if _, ok := index[`Band (1960) "Loud"`]; !ok {
+		t.Errorf("addPlays did not find the album of a CUE track: %v", index)
+	}
+}
+
+func TestAlbumPlays(t *testing.T) {
+	ap := AlbumPlays{}
+	for _, minutes := range []int{30, 0, 5, 300, 5} {
+		ap.addPlay(testNow.Add(time.Duration(minutes) * time.Minute))
+	}
+	if len(ap.History) != 4 || !ap.LastPlayed().Equal(testNow.Add(300*time.Minute)) {
+		t.Errorf("addPlay returned history %v", ap.History)
+	}
+	if ap.Count() != 2 {
+		t.Errorf("Count returned %d, expected 2 sessions", ap.Count())
+	}
+	ap.Stickers = 7
+	if ap.Count() != 7 {
+		t.Errorf("Count returned %d, expected the sticker count", ap.Count())
+	}
+}
+
+func TestSetStickers(t *testing.T) {
+	index := PlayIndex{"Old (2000) Stale": &AlbumPlays{Stickers: 4}}
+	albums := index.setStickers("/music",
+		map[string]string{"Jazz/Miles/Miles (1959) Blue/01.flac": "3", "Jazz/Miles/Miles (1959) Blue/02.flac": "2", "junk/01.flac": "1"},
+		map[string]string{"Jazz/Miles/Miles (1959) Blue/01.flac": "8", "Jazz/Miles/Miles (1959) Blue/02.flac": "x"})
+	if albums != 1 {
+		t.Errorf("setStickers updated %d albums, expected 1", albums)
+	}
+	blue := index["Miles (1959) Blue"]
+	if blue == nil || blue.Stickers != 3 || blue.Rating != 8 {
+		t.Errorf("setStickers returned %v", blue)
+	}
+	if index["Old (2000) Stale"].Stickers != 0 {
+		t.Errorf("setStickers should have reset stale stickers")
+	}
+}
+
+func TestPlayIndexSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_plays")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "radis_plays.yaml")
+	index, err := LoadPlayIndex(path)
+	if err != nil || len(index) != 0 {
+		t.Fatalf("LoadPlayIndex of a missing file returned %v, %v", index, err)
+	}
+	index["Miles (1959) Blue"] = &AlbumPlays{Rating: 8, History: []time.Time{testNow}}
+	index["Band (1960) Loud"] = &AlbumPlays{}
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPlayIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blue := Album{Root: "/music", Path: "/music/Jazz/Miles/Miles (1959) Blue[MP3]"}
+	if ap := loaded.Of(blue); len(loaded) != 1 || ap.Rating != 8 || !ap.LastPlayed().Equal(testNow) {
+		t.Errorf("LoadPlayIndex returned %v", loaded)
+	}
+}
+
+func TestSmartPlaylistPlays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_smart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sc := config.Config{
+		Paths:  config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: dir},
+		Genres: config.Genres{config.Genre{Name: "Jazz", Artists: []string{"Miles"}}},
+	}
+	albums := createTestAlbums(t, sc, "Jazz/Miles/Miles (1959) Blue", "Jazz/Miles/Miles (1970) Brew")
+	plays := PlayIndex{"Miles (1959) Blue": &AlbumPlays{Stickers: 3}}
+
+	never := config.SmartPlaylist{Name: "never", CountsPlays: true}
+	p, err := generateSmartPlaylist(sc, never, albums, plays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.contents) != 1 || p.contents[0].Album.title != "Brew" {
+		t.Errorf("generateSmartPlaylist returned %v, expected only unplayed albums", p.contents)
+	}
+}
@@ -0,0 +1,232 @@
+package music
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/barsanuphe/radis/directory"
+	"github.com/ttacon/chalk"
+)
+
+// Reasons why a broken playlist entry was matched to a track of the collection.
+const (
+	matchedAlbum    = "same album"
+	matchedTitle    = "same title"
+	matchedChecksum = "same checksum"
+)
+
+// collectionIndex finds albums and files of the collection to repair playlists.
+type collectionIndex struct {
+	albums []Album
+	// checksums of the tracks of playlists, when they were written.
+	checksums checksumIndex
+	// bySize maps file sizes to the music files of the collection, built when needed.
+	bySize map[int64][]string
+	// albumOf maps music files to the index of their album.
+	albumOf map[string]int
+}
+
+// newCollectionIndex scans the collection, and reads the checksums kept with the playlists.
+func newCollectionIndex(c config.Config) (index *collectionIndex, err error) {
+	albums, err := getAlbums(c)
+	if err != nil {
+		return
+	}
+	checksums, err := loadChecksumIndex(c.Paths.MPDPlaylistDirectory)
+	if err != nil {
+		return
+	}
+	return &collectionIndex{albums: albums, checksums: checksums}, nil
+}
+
+// buildFileIndex lists the music files of the collection by size.
+func (ci *collectionIndex) buildFileIndex() (err error) {
+	ci.bySize = make(map[int64][]string)
+	ci.albumOf = make(map[string]int)
+	for i, a := range ci.albums {
+		files, err := getMusicFiles(a.Path)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			fileInfo, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			ci.bySize[fileInfo.Size()] = append(ci.bySize[fileInfo.Size()], file)
+			ci.albumOf[file] = i
+		}
+	}
+	return
+}
+
+// findTrackFile returns the name of a track in an album, allowing a different extension.
+func findTrackFile(a Album, filename string) (string, bool) {
+	if filename == "" {
+		// whole album
+		return "", true
+	}
+	existing := filename
+	if isVirtualTrack(filename) {
+		existing = filepath.Dir(filename)
+	}
+	if _, err := os.Stat(filepath.Join(a.Path, existing)); err == nil {
+		return filename, true
+	}
+	if isVirtualTrack(filename) {
+		return "", false
+	}
+	stem := strings.ToLower(strings.TrimSuffix(filename, filepath.Ext(filename)))
+	files, err := getMusicFiles(a.Path)
+	if err != nil {
+		return "", false
+	}
+	for _, file := range files {
+		base := filepath.Base(file)
+		if strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base))) == stem {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// findByName looks for the album of a track by its parsed name: first the exact album,
+// then the same title with a different year or format flag.
+func (ci *collectionIndex) findByName(t Track) (match Track, reason string, found bool) {
+	if !t.Album.IsValidAlbum() {
+		return
+	}
+	for _, reason := range []string{matchedAlbum, matchedTitle} {
+		for _, a := range ci.albums {
+			if a.normalizedName() != t.Album.normalizedName() {
+				continue
+			}
+			if reason == matchedAlbum && (a.year != t.Album.year || a.IsMP3 != t.Album.IsMP3) {
+				continue
+			}
+			if filename, ok := findTrackFile(a, t.Filename); ok {
+				return Track{Album: a, Filename: filename}, reason, true
+			}
+		}
+	}
+	return
+}
+
+// findByChecksum looks for a track file with the checksum it had when a playlist was last written,
+// or, if it still exists outside of a valid album, with its current checksum.
+func (ci *collectionIndex) findByChecksum(root, relativePath string) (match Track, reason string, found bool) {
+	file := filepath.Join(root, relativePath)
+	sum, known := ci.checksums[relativePath]
+	if !known {
+		fileInfo, err := os.Stat(file)
+		if err != nil || fileInfo.IsDir() {
+			return
+		}
+		checksum, err := fileChecksum(file)
+		if err != nil {
+			return
+		}
+		sum = fileSum{Size: fileInfo.Size(), Checksum: checksum}
+	}
+	if sum.Size == 0 {
+		return
+	}
+	if ci.bySize == nil {
+		if err := ci.buildFileIndex(); err != nil {
+			return
+		}
+	}
+	for _, candidate := range ci.bySize[sum.Size] {
+		if candidate == file {
+			continue
+		}
+		if candidateChecksum, err := fileChecksum(candidate); err == nil && candidateChecksum == sum.Checksum {
+			return Track{Album: ci.albums[ci.albumOf[candidate]], Filename: filepath.Base(candidate)}, matchedChecksum, true
+		}
+	}
+	return
+}
+
+// findMatch returns the best match in the collection for a track that cannot be found.
+func (ci *collectionIndex) findMatch(root string, t Track) (match Track, reason string, found bool) {
+	if match, reason, found = ci.findByName(t); found {
+		return
+	}
+	if t.IsAlbum() {
+		return
+	}
+	return ci.findByChecksum(root, t.String())
+}
+
+// repair points the tracks of a loaded playlist that cannot be found to their best match in the collection.
+// It returns the number of tracks remapped to their synced album, of repaired tracks, and the entries that could not be resolved.
+func (p *Playlist) repair(c config.Config, ci *collectionIndex) (remapped, repaired int, unresolved []string, err error) {
+	report, err := p.Remap(c)
+	if err != nil {
+		return
+	}
+	remapped = report.Remapped
+	for i := range p.contents {
+		t := &p.contents[i]
+		if !t.missing {
+			continue
+		}
+		match, reason, found := ci.findMatch(c.Paths.Root, *t)
+		if !found {
+			unresolved = append(unresolved, t.String())
+			continue
+		}
+		relativePath, _ := filepath.Rel(c.Paths.Root, filepath.Join(match.Album.Path, match.Filename))
+		fmt.Printf("%s+ %s\n\t -> %s (%s)\n%s", chalk.Yellow, t.String(), relativePath, reason, chalk.Reset)
+		// the album is where it was found, synced or not
+		match.Album.NewPath = match.Album.Path
+		*t = match
+		repaired++
+	}
+	return
+}
+
+// RepairPlaylists finds the best match in the collection for the entries of playlists that cannot be found.
+// All playlists of MPDPlaylistDirectory are repaired if no filename is given.
+func RepairPlaylists(c config.Config, filename string) (err error) {
+	defer timeTrack(time.Now(), "Repairing playlists")
+
+	files := []string{filename}
+	if filename == "" {
+		if files, err = directory.GetPlaylists(c.Paths.MPDPlaylistDirectory); err != nil {
+			return
+		}
+	}
+	ci, err := newCollectionIndex(c)
+	if err != nil {
+		return
+	}
+	totalRepaired, totalUnresolved := 0, 0
+	for _, file := range files {
+		p := Playlist{Filename: filepath.Join(c.Paths.MPDPlaylistDirectory, file)}
+		if err = p.Load(c.Paths.Root); err != nil {
+			return
+		}
+		fmt.Println(chalk.Blue.Color("Repairing " + file))
+		remapped, repaired, unresolved, err := p.repair(c, ci)
+		if err != nil {
+			return err
+		}
+		for _, entry := range unresolved {
+			fmt.Println(chalk.Red.Color("!!! Could not resolve " + entry))
+		}
+		if remapped != 0 || repaired != 0 {
+			if err := p.Write(); err != nil {
+				return err
+			}
+		}
+		totalRepaired += repaired
+		totalUnresolved += len(unresolved)
+	}
+	fmt.Printf("\n### Repaired %d entries in %d playlists, %d could not be resolved.\n", totalRepaired, len(files), totalUnresolved)
+	return
+}
@@ -0,0 +1,108 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+func TestRepairPlaylists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_repair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rc := config.Config{
+		Paths:  config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: dir},
+		Genres: config.Genres{config.Genre{Name: "genre1", Artists: []string{"artist"}}},
+	}
+	files := map[string]string{
+		"genre1/artist/artist (2001) remaster/01.flac":   "remaster",
+		"genre1/artist/artist (2000) other [MP3]/01.mp3": "mp3",
+		"genre1/artist/artist (2002) copy/05.flac":       "same contents",
+		"stray/05.flac": "same contents",
+	}
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename := filepath.Join(dir, "broken.m3u")
+	content := "genre1/artist/artist (1999) remaster/01.flac\n" +
+		"genre1/artist/artist (2000) other/01.flac\n" +
+		"stray/05.flac\n" +
+		"genre1/artist/artist (1990) lost/01.flac\n"
+	if err := ioutil.WriteFile(filename, []byte(content), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RepairPlaylists(rc, "broken.m3u"); err != nil {
+		t.Fatal(err)
+	}
+	written, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "genre1/artist/artist (2001) remaster/01.flac\n" +
+		"genre1/artist/artist (2000) other [MP3]/01.mp3\n" +
+		"genre1/artist/artist (2002) copy/05.flac\n" +
+		"genre1/artist/artist (1990) lost/01.flac\n"
+	if string(written) != expected {
+		t.Errorf("RepairPlaylists(%s) wrote %s, expected %s", filename, string(written), expected)
+	}
+
+	// an album that was synced elsewhere, without anything to repair
+	filename = filepath.Join(dir, "moved.m3u")
+	if err := ioutil.WriteFile(filename, []byte("genre2/artist/artist (2001) remaster/01.flac\n"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := RepairPlaylists(rc, "moved.m3u"); err != nil {
+		t.Fatal(err)
+	}
+	written, err = ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "genre1/artist/artist (2001) remaster/01.flac\n"
+	if string(written) != expected {
+		t.Errorf("RepairPlaylists(%s) wrote %s, expected %s", filename, string(written), expected)
+	}
+
+	// a file that moved to another album after the playlist was written, found by its checksum
+	filename = filepath.Join(dir, "checksum.m3u")
+	if err := ioutil.WriteFile(filename, []byte("genre1/artist/artist (2002) copy/05.flac\n"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	pl := Playlist{Filename: filename}
+	if _, err := pl.UpdateAndSave(rc); err != nil {
+		t.Fatal(err)
+	}
+	moved := filepath.Join(dir, "genre1/artist/artist (2003) best of/12.flac")
+	if err := os.MkdirAll(filepath.Dir(moved), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "genre1/artist/artist (2002) copy/05.flac"), moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "stray/05.flac")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RepairPlaylists(rc, "checksum.m3u"); err != nil {
+		t.Fatal(err)
+	}
+	written, err = ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "genre1/artist/artist (2003) best of/12.flac\n"
+	if string(written) != expected {
+		t.Errorf("RepairPlaylists(%s) wrote %s, expected %s", filename, string(written), expected)
+	}
+}
@@ -0,0 +1,262 @@
+package music
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/barsanuphe/radis/directory"
+)
+
+// Rippers that write logs radis can read.
+const (
+	rippedWithEAC = "EAC"
+	rippedWithXLD = "XLD"
+)
+
+var (
+	trackHeaderPattern = regexp.MustCompile(`^Track\s+(\d+)$`)
+	// XLD lists error counters for each track
+	xldErrorPattern = regexp.MustCompile(`^(Read error|Skipped \(treated as error\)|Inconsistency in error sectors|Damaged sector count)\s*:\s*(\d+)`)
+	// EAC describes problems for each track
+	eacErrors = []string{"Suspicious position", "Missing samples", "Timing problem", "Copy aborted"}
+	// EAC range rips have one section for the whole disc, and an AccurateRip summary for each track
+	eacRangeHeader             = "Range status and errors"
+	eacRangeAccurateRipPattern = regexp.MustCompile(`(?i)^Track\s+\d+\s+(accurately ripped|cannot be verified as accurate|not present in)`)
+)
+
+// RipLog holds what can be found in an EAC or XLD log file.
+// An EAC range rip counts as one track; Copied counts the tracks with a copy status,
+// CopiedOK those EAC copied without problems.
+type RipLog struct {
+	Path              string
+	Ripper            string
+	Drive             string
+	ReadMode          string
+	Tracks            int
+	TestedTracks      int
+	Copied            int
+	CopiedOK          int
+	CRCMismatches     int
+	AccurateRipOK     int
+	AccurateRipFailed int
+	AccurateRipAbsent int
+	Errors            []string
+}
+
+// String gives a representation of a RipLog.
+func (r *RipLog) String() (txt string) {
+	txt = fmt.Sprintf("%s (%s, score %d)\n", filepath.Base(r.Path), r.Ripper, r.Score())
+	txt += "\tDrive: " + r.Drive + "\n"
+	txt += "\tRead mode: " + r.ReadMode + "\n"
+	txt += fmt.Sprintf("\tTracks: %d, tested: %d, CRC mismatches: %d\n", r.Tracks, r.TestedTracks, r.CRCMismatches)
+	if r.Copied != 0 {
+		txt += fmt.Sprintf("\tCopy: %d ok out of %d\n", r.CopiedOK, r.Copied)
+	}
+	txt += fmt.Sprintf("\tAccurateRip: %d ok, %d failed, %d not in database\n", r.AccurateRipOK, r.AccurateRipFailed, r.AccurateRipAbsent)
+	for _, e := range r.Errors {
+		txt += "\tError: " + e + "\n"
+	}
+	return
+}
+
+// IsSecure indicates if the rip was made in a secure read mode.
+func (r *RipLog) IsSecure() bool {
+	return strings.Contains(r.ReadMode, "Secure") || strings.Contains(r.ReadMode, "Paranoia")
+}
+
+// HasFailedAccurateRip indicates if some tracks did not match the AccurateRip database.
+func (r *RipLog) HasFailedAccurateRip() bool {
+	return r.AccurateRipFailed != 0
+}
+
+// Score rates a rip out of 100.
+func (r *RipLog) Score() (score int) {
+	score = 100
+	if !r.IsSecure() {
+		score -= 40
+	}
+	if r.Tracks == 0 || r.TestedTracks < r.Tracks {
+		score -= 10
+	}
+	score -= 20 * r.CRCMismatches
+	score -= 20 * r.AccurateRipFailed
+	score -= 10 * len(r.Errors)
+	if score < 0 {
+		score = 0
+	}
+	return
+}
+
+// RipScore returns the lowest score of an album's rip logs, or -1 if it has none.
+func RipScore(logs []RipLog) (score int) {
+	score = -1
+	for _, r := range logs {
+		if score == -1 || r.Score() < score {
+			score = r.Score()
+		}
+	}
+	return
+}
+
+// decodeText returns the text of UTF-8 or UTF-16 data with a BOM, such as the logs EAC writes.
+func decodeText(data []byte) string {
+	var isBigEndian bool
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		isBigEndian = false
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		isBigEndian = true
+	default:
+		return string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF}))
+	}
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if isBigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// ParseRipLog reads an EAC or XLD log file.
+func ParseRipLog(path string) (r RipLog, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	r, err = parseRipLog(decodeText(data))
+	r.Path = path
+	return
+}
+
+// parseRipLog extracts information from the text of a log.
+func parseRipLog(text string) (r RipLog, err error) {
+	switch {
+	case strings.Contains(text, "Exact Audio Copy"):
+		r.Ripper = rippedWithEAC
+	case strings.Contains(text, "X Lossless Decoder"):
+		r.Ripper = rippedWithXLD
+	default:
+		return r, errors.New("Unknown log format")
+	}
+
+	track := 0
+	isRange := false
+	var testCRC, copyCRC string
+	endTrack := func() {
+		if track == 0 {
+			return
+		}
+		if testCRC != "" {
+			r.TestedTracks++
+			if testCRC != copyCRC {
+				r.CRCMismatches++
+			}
+		}
+		testCRC, copyCRC = "", ""
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		key, value := line, ""
+		if i := strings.Index(line, ":"); i != -1 {
+			key, value = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+
+		switch {
+		case key == "Used drive" && r.Drive == "":
+			r.Drive = value
+		case (key == "Read mode" || key == "Ripper mode") && r.ReadMode == "":
+			r.ReadMode = value
+		case trackHeaderPattern.MatchString(line), line == eacRangeHeader:
+			endTrack()
+			track++
+			r.Tracks++
+			isRange = line == eacRangeHeader
+		}
+		if track == 0 {
+			// AccurateRip summaries and errors only count inside track or range sections
+			continue
+		}
+		if isRange {
+			if matches := eacRangeAccurateRipPattern.FindStringSubmatch(line); len(matches) != 0 {
+				switch strings.ToLower(matches[1]) {
+				case "accurately ripped":
+					r.AccurateRipOK++
+				case "cannot be verified as accurate":
+					r.AccurateRipFailed++
+				default:
+					r.AccurateRipAbsent++
+				}
+				continue
+			}
+		}
+		section := fmt.Sprintf("track %d", track)
+		if isRange {
+			section = "range"
+		}
+
+		switch {
+		case strings.HasPrefix(line, "Test CRC"):
+			testCRC = strings.TrimSpace(strings.TrimPrefix(line, "Test CRC"))
+		case strings.HasPrefix(line, "Copy CRC"):
+			copyCRC = strings.TrimSpace(strings.TrimPrefix(line, "Copy CRC"))
+		case key == "CRC32 hash (test run)":
+			testCRC = value
+		case key == "CRC32 hash":
+			copyCRC = value
+		case line == "Copy OK":
+			r.Copied++
+			r.CopiedOK++
+		case line == "Copy finished", strings.HasPrefix(line, "Copy aborted"):
+			r.Copied++
+		case strings.Contains(line, "Accurately ripped"):
+			r.AccurateRipOK++
+		case strings.Contains(line, "Cannot be verified as accurate"), strings.Contains(line, "Rip may not be accurate"):
+			r.AccurateRipFailed++
+		case strings.Contains(line, "not present in"):
+			r.AccurateRipAbsent++
+		}
+		if matches := xldErrorPattern.FindStringSubmatch(line); len(matches) != 0 && matches[2] != "0" {
+			r.Errors = append(r.Errors, section+": "+line)
+		}
+		for _, e := range eacErrors {
+			if strings.HasPrefix(line, e) {
+				r.Errors = append(r.Errors, section+": "+line)
+			}
+		}
+	}
+	endTrack()
+	return
+}
+
+// GetRipLogs returns the parsed logs found in an album directory.
+func (a *Album) GetRipLogs() (logs []RipLog, err error) {
+	files, err := directory.GetFiles(a.Path)
+	if err != nil {
+		return
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if strings.ToLower(filepath.Ext(file)) != ".log" {
+			continue
+		}
+		r, err := ParseRipLog(filepath.Join(a.Path, file))
+		if err != nil {
+			// not a rip log
+			continue
+		}
+		logs = append(logs, r)
+	}
+	return
+}
@@ -0,0 +1,178 @@
+package music
+
+import (
+	"testing"
+	"unicode/utf16"
+)
+
+const testEACLog = `Exact Audio Copy V1.0 beta 3 from 29. August 2011
+
+Used drive  : PLEXTOR DVDR   PX-716A   Adapter: 1  ID: 0
+
+Read mode               : Secure
+Utilize accurate stream : Yes
+
+Track  1
+
+     Filename C:\rips\01 - One.wav
+
+     Peak level 98.8 %
+     Test CRC 1A2B3C4D
+     Copy CRC 1A2B3C4D
+     Accurately ripped (confidence 5)  [ABCDEF12]  (AR v2)
+     Copy OK
+
+Track  2
+
+     Filename C:\rips\02 - Two.wav
+
+     Suspicious position 0:02:20
+
+     Peak level 97.1 %
+     Test CRC 11111111
+     Copy CRC 22222222
+     Cannot be verified as accurate (confidence 2)  [12345678], AccurateRip returned [87654321]  (AR v2)
+     Copy finished
+
+Some tracks could not be verified as accurate
+
+There were errors
+
+End of status report
+`
+
+const testEACRangeLog = `Exact Audio Copy V1.0 beta 3 from 29. August 2011
+
+Used drive  : PLEXTOR DVDR   PX-716A   Adapter: 1  ID: 0
+
+Read mode               : Secure
+
+Range status and errors
+
+Selected range
+
+     Filename C:\rips\Album.wav
+
+     Suspicious position 0:12:03
+
+     Peak level 100.0 %
+     Range quality 99.9 %
+     Test CRC 9A8B7C6D
+     Copy CRC 9A8B7C6D
+     Copy finished
+
+There were errors
+
+AccurateRip summary
+
+Track  1  accurately ripped (confidence 5)  [ABCDEF12]  (AR v2)
+Track  2  cannot be verified as accurate (confidence 2)  [12345678], AccurateRip returned [87654321]  (AR v2)
+Track  3  not present in database
+
+Some tracks could not be verified as accurate
+
+End of status report
+`
+
+const testXLDLog = `X Lossless Decoder version 20121027 (144.0)
+
+Used drive : HL-DT-ST DVDRW GA32N (revision DE01)
+Ripper mode             : XLD Secure Ripper
+
+AccurateRip Summary (DiscID: 0012abcd-00ab12cd-0a0b0c0d)
+    Track 01 : OK (A2, AccurateRip signature: 12345678)
+    ->All tracks accurately ripped.
+
+Track 01
+    Filename : /rips/01 - One.flac
+    CRC32 hash (test run)  : 1A2B3C4D
+    CRC32 hash             : 1A2B3C4D
+    AccurateRip v2 signature : 12345678
+        ->Accurately ripped (v2, confidence 7/7)
+    Statistics
+        Read error                           : 0
+        Skipped (treated as error)           : 0
+        Damaged sector count                 : 0
+
+No errors occurred
+
+End of status report
+`
+
+func encodeUTF16(text string) []byte {
+	data := []byte{0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+	return data
+}
+
+var testRipLogs = []struct {
+	text     string
+	expected RipLog
+	score    int
+}{
+	{
+		decodeText(encodeUTF16(testEACLog)),
+		RipLog{
+			Ripper:            rippedWithEAC,
+			Drive:             "PLEXTOR DVDR   PX-716A   Adapter: 1  ID: 0",
+			ReadMode:          "Secure",
+			Tracks:            2,
+			TestedTracks:      2,
+			CRCMismatches:     1,
+			AccurateRipOK:     1,
+			Copied:            2,
+			CopiedOK:          1,
+			AccurateRipFailed: 1,
+			Errors:            []string{"track 2: Suspicious position 0:02:20"},
+		},
+		50,
+	},
+	{
+		testEACRangeLog,
+		RipLog{
+			Ripper:            rippedWithEAC,
+			Drive:             "PLEXTOR DVDR   PX-716A   Adapter: 1  ID: 0",
+			ReadMode:          "Secure",
+			Tracks:            1,
+			TestedTracks:      1,
+			Copied:            1,
+			AccurateRipOK:     1,
+			AccurateRipFailed: 1,
+			AccurateRipAbsent: 1,
+			Errors:            []string{"range: Suspicious position 0:12:03"},
+		},
+		70,
+	},
+	{
+		testXLDLog,
+		RipLog{
+			Ripper:        rippedWithXLD,
+			Drive:         "HL-DT-ST DVDRW GA32N (revision DE01)",
+			ReadMode:      "XLD Secure Ripper",
+			Tracks:        1,
+			TestedTracks:  1,
+			AccurateRipOK: 1,
+		},
+		100,
+	},
+}
+
+func TestParseRipLog(t *testing.T) {
+	for _, tr := range testRipLogs {
+		r, err := parseRipLog(tr.text)
+		if err != nil {
+			t.Errorf("parseRipLog returned an error: %s", err.Error())
+		}
+		if r.String() != tr.expected.String() {
+			t.Errorf("parseRipLog returned %s, expected %s", r.String(), tr.expected.String())
+		}
+		if r.Score() != tr.score {
+			t.Errorf("Score(%s) returned %d, expected %d", r.Ripper, r.Score(), tr.score)
+		}
+	}
+	if _, err := parseRipLog("not a log"); err == nil {
+		t.Errorf("parseRipLog should have returned an error for an unknown format")
+	}
+}
@@ -0,0 +1,178 @@
+package music
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/ttacon/chalk"
+)
+
+// scrobble is a track listened to on a portable player, as written in .scrobbler.log.
+type scrobble struct {
+	artist string
+	album  string
+	at     time.Time
+}
+
+// parseScrobblerLog reads the AudioScrobbler portable player log format written by Rockbox.
+// Skipped tracks are ignored. If the player did not know its time zone, timestamps are local times.
+func parseScrobblerLog(r io.Reader, location *time.Location) (scrobbles []scrobble, err error) {
+	scanner := bufio.NewScanner(r)
+	isUTC := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			if strings.HasPrefix(line, "#TZ/") {
+				isUTC = strings.TrimPrefix(line, "#TZ/") == "UTC"
+			}
+			continue
+		}
+		// ARTIST ALBUM TITLE TRACKNUM LENGTH RATING TIMESTAMP [MUSICBRAINZ_TRACKID]
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		if fields[5] != "L" {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(fields[6], 10, 64)
+		if err != nil {
+			return nil, errors.New("Invalid timestamp in scrobbler log: " + line)
+		}
+		at := time.Unix(timestamp, 0).UTC()
+		if !isUTC {
+			at = time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), at.Minute(), at.Second(), 0, location)
+		}
+		scrobbles = append(scrobbles, scrobble{artist: fields[0], album: fields[1], at: at})
+	}
+	return scrobbles, scanner.Err()
+}
+
+// normalizeName makes names comparable despite case, punctuation and characters that cannot be in directory names.
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// albumFinder finds the albums of the collection by artist and title.
+type albumFinder struct {
+	aliases  config.Aliases
+	byArtist map[string]Album
+	byTitle  map[string][]Album
+}
+
+// newAlbumFinder indexes albums, which must have their aliases resolved.
+func newAlbumFinder(c config.Config, albums []Album) *albumFinder {
+	af := &albumFinder{aliases: c.Aliases, byArtist: make(map[string]Album), byTitle: make(map[string][]Album)}
+	for _, a := range albums {
+		title := normalizeName(a.title)
+		af.byArtist[normalizeName(a.mainAlias)+"/"+title] = a
+		af.byArtist[normalizeName(a.artist)+"/"+title] = a
+		af.byTitle[title] = append(af.byTitle[title], a)
+	}
+	return af
+}
+
+// mainAlias returns the main alias of an artist, ignoring case and punctuation.
+func (af *albumFinder) mainAlias(artist string) string {
+	name := normalizeName(artist)
+	for _, alias := range af.aliases {
+		for _, a := range alias.Aliases {
+			if normalizeName(a) == name {
+				return alias.MainAlias
+			}
+		}
+	}
+	return artist
+}
+
+// find an album by artist and title.
+// Compilations, or any album if the scrobble is by Various Artists, are found by title alone, if only one of them has it.
+func (af *albumFinder) find(artist, title string) (a Album, ok bool) {
+	title = normalizeName(title)
+	mainAlias := normalizeName(af.mainAlias(artist))
+	if a, ok = af.byArtist[mainAlias+"/"+title]; ok {
+		return
+	}
+	isCompilation := mainAlias == normalizeName("Various Artists")
+	candidates := []Album{}
+	for _, candidate := range af.byTitle[title] {
+		if isCompilation || candidate.mainAlias == "Various Artists" {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	return
+}
+
+// addScrobbles adds the scrobbles of albums of the collection to the play history, and returns what could not be matched.
+func (pi PlayIndex) addScrobbles(af *albumFinder, scrobbles []scrobble) (added int, unknown []string) {
+	unknownAlbums := make(map[string]bool)
+	for _, s := range scrobbles {
+		a, ok := af.find(s.artist, s.album)
+		if !ok {
+			unknownAlbums[s.artist+" - "+s.album] = true
+			continue
+		}
+		if pi.get(playKey(a)).addPlay(s.at) {
+			added++
+		}
+	}
+	for name := range unknownAlbums {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	return
+}
+
+// ImportScrobblerLog adds the plays of a .scrobbler.log file to the play statistics.
+// Plays already imported are ignored, so the same log can be imported again.
+func ImportScrobblerLog(c config.Config, filename string) (err error) {
+	defer timeTrack(time.Now(), "Importing plays")
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	scrobbles, err := parseScrobblerLog(f, time.Local)
+	f.Close()
+	if err != nil {
+		return
+	}
+	path, err := c.PlayIndexFile()
+	if err != nil {
+		return
+	}
+	index, err := LoadPlayIndex(path)
+	if err != nil {
+		return
+	}
+	albums, err := getAlbums(c)
+	if err != nil {
+		return
+	}
+	added, unknown := index.addScrobbles(newAlbumFinder(c, albums), scrobbles)
+	for _, name := range unknown {
+		fmt.Println(chalk.Yellow.Color("- Not in the collection: " + name))
+	}
+	if err = index.Save(path); err != nil {
+		return
+	}
+	fmt.Printf("\n### Imported %d new plays out of %d, %d albums not found.\n", added, len(scrobbles), len(unknown))
+	return
+}
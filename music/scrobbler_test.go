@@ -0,0 +1,113 @@
+package music
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+const testScrobblerLog = "#AUDIOSCROBBLER/1.1\n#TZ/UNKNOWN\n#CLIENT/Rockbox ipodvideo $Revision$\n" +
+	"Thom Yorke\tThe Eraser\tAnalyse\t2\t242\tL\t1456826400\t\n" +
+	"Radiohead\tOK Computer\tAirbag\t1\t284\tS\t1456826700\t\n" +
+	"MF DOOM\tMm..Food\tBeef Rap\t2\t260\tL\t1456827000\n" +
+	"Various\tRare Chicago Blues\tSweet Home\t1\t180\tL\t1456827300\t\n" +
+	"Unknown\tNowhere\tNothing\t1\t180\tL\t1456827600\t\n"
+
+func TestParseScrobblerLog(t *testing.T) {
+	scrobbles, err := parseScrobblerLog(strings.NewReader(testScrobblerLog), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scrobbles) != 4 {
+		t.Fatalf("parseScrobblerLog returned %d scrobbles, expected 4 without the skipped track", len(scrobbles))
+	}
+	if !scrobbles[0].at.Equal(time.Date(2016, time.March, 1, 10, 0, 0, 0, time.UTC)) || scrobbles[0].album != "The Eraser" {
+		t.Errorf("parseScrobblerLog returned %v", scrobbles[0])
+	}
+	// without a time zone, timestamps are local times
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	scrobbles, err = parseScrobblerLog(strings.NewReader(testScrobblerLog), paris)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scrobbles[0].at.Equal(time.Date(2016, time.March, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseScrobblerLog returned %s for a local time", scrobbles[0].at)
+	}
+}
+
+func TestAddScrobbles(t *testing.T) {
+	sc := config.Config{
+		Aliases: config.Aliases{
+			config.Artist{MainAlias: "Radiohead", Aliases: []string{"Thom Yorke"}},
+			config.Artist{MainAlias: "MF DOOM", Aliases: []string{"Madvillain"}},
+		},
+	}
+	albums := []Album{}
+	for _, path := range []string{
+		"/music/Rock/Radiohead/Thom Yorke (2006) The Eraser",
+		"/music/Hip-Hop/MF DOOM/MF DOOM (2004) Mm-Food",
+		"/music/Blues/Various Artists/Various Artists (1990) Rare Chicago Blues",
+	} {
+		a := Album{Root: "/music", Path: path}
+		if _, err := a.FindNewPath(sc); err != nil {
+			t.Fatal(err)
+		}
+		albums = append(albums, a)
+	}
+	scrobbles, err := parseScrobblerLog(strings.NewReader(testScrobblerLog), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := PlayIndex{"MF DOOM (2004) Mm-Food": &AlbumPlays{History: []time.Time{scrobbles[1].at}}}
+	added, unknown := index.addScrobbles(newAlbumFinder(sc, albums), scrobbles)
+	if added != 2 {
+		t.Errorf("addScrobbles added %d plays, expected 2", added)
+	}
+	if len(unknown) != 1 || unknown[0] != "Unknown - Nowhere" {
+		t.Errorf("addScrobbles could not find %v", unknown)
+	}
+	for _, key := range []string{"Thom Yorke (2006) The Eraser", "MF DOOM (2004) Mm-Food", "Various Artists (1990) Rare Chicago Blues"} {
+		if ap, ok := index[key]; !ok || len(ap.History) != 1 {
+			t.Errorf("addScrobbles did not add one play to %s: %v", key, index)
+		}
+	}
+}
+
+var testFindAlbums = []struct {
+	artist   string
+	title    string
+	expected string
+}{
+	{"Someone", "Greatest Hits", "Someone (2000) Greatest Hits"},
+	{"Various", "Rare Chicago Blues", "Various Artists (1990) Rare Chicago Blues"},
+	{"Various Artists", "Greatest Hits", "Someone (2000) Greatest Hits"},
+	// another artist with an album of the same title
+	{"Anyone", "Greatest Hits", ""},
+}
+
+func TestFindAlbum(t *testing.T) {
+	albums := []Album{}
+	for _, path := range []string{
+		"/music/Pop/Someone/Someone (2000) Greatest Hits",
+		"/music/Blues/Various Artists/Various Artists (1990) Rare Chicago Blues",
+	} {
+		a := Album{Root: "/music", Path: path}
+		if _, err := a.FindNewPath(config.Config{}); err != nil {
+			t.Fatal(err)
+		}
+		albums = append(albums, a)
+	}
+	af := newAlbumFinder(config.Config{}, albums)
+	for _, tf := range testFindAlbums {
+		a, ok := af.find(tf.artist, tf.title)
+		if ok != (tf.expected != "") || (ok && playKey(a) != tf.expected) {
+			t.Errorf("find(%s, %s) returned %s, %t, expected %s", tf.artist, tf.title, playKey(a), ok, tf.expected)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package music
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/barsanuphe/radis/config"
+	"github.com/ttacon/chalk"
+)
+
+// format returns the format of the album, as used by smart playlists.
+func (a *Album) format() string {
+	if a.IsMP3 {
+		return config.FormatMP3
+	}
+	return config.FormatFLAC
+}
+
+// addedOn returns when the album was added to the collection, from its directory modification time.
+func (a *Album) addedOn() (added time.Time, err error) {
+	fileInfo, err := os.Stat(a.Path)
+	if err != nil {
+		return
+	}
+	return fileInfo.ModTime(), nil
+}
+
+// matches checks if an album satisfies the rules of a smart playlist, with its play statistics.
+func (a *Album) matches(s config.SmartPlaylist, plays PlayIndex) bool {
+	if !a.IsValidAlbum() {
+		return false
+	}
+	year, err := strconv.Atoi(a.year)
+	if err != nil {
+		return false
+	}
+	if !s.HasGenre(a.genre) || !s.HasYear(year) || !s.HasFormat(a.format()) {
+		return false
+	}
+	if !s.HasArtist(a.artist) && !s.HasArtist(a.mainAlias) {
+		return false
+	}
+	if s.AddedWithin != 0 {
+		added, err := a.addedOn()
+		if err != nil || !s.IsRecent(added) {
+			return false
+		}
+	}
+	if s.UsesPlays() {
+		ap := plays.Of(*a)
+		if !s.HasPlayCount(ap.Count()) || !s.HasLastPlayed(ap.LastPlayed()) || !s.HasRating(ap.Rating) {
+			return false
+		}
+	}
+	return true
+}
+
+// smartPlaylistFilename returns where a smart playlist is written, as a .m3u file unless its name has a known extension.
+func smartPlaylistFilename(c config.Config, name string) string {
+	if _, err := playlistFormat(name); err != nil {
+		name += ".m3u"
+	}
+	return filepath.Join(c.Paths.MPDPlaylistDirectory, name)
+}
+
+// generateSmartPlaylist writes the albums matching a smart playlist.
+// An existing playlist is removed if no albums match anymore.
+func generateSmartPlaylist(c config.Config, s config.SmartPlaylist, albums []Album, plays PlayIndex) (p Playlist, err error) {
+	p = Playlist{Filename: smartPlaylistFilename(c, s.Name), AlbumLevel: true}
+	for _, a := range albums {
+		if a.matches(s, plays) {
+			// the album is where it is now, synced or not
+			a.NewPath = a.Path
+			p.AddAlbum(a)
+		}
+	}
+	if len(p.contents) == 0 {
+		if err = os.Remove(p.Filename); os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	if s.Order != "" {
+		seed := s.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		if err = p.Order(c, s.Order, s.Reverse, seed); err != nil {
+			return
+		}
+	}
+	err = p.Write()
+	return
+}
+
+// GenerateSmartPlaylists scans the music collection root and writes the playlists defined in radis_playlists.yaml.
+func GenerateSmartPlaylists(c config.Config) (err error) {
+	defer timeTrack(time.Now(), "Generating playlists")
+
+	if len(c.Playlists) == 0 {
+		fmt.Println("No smart playlists defined.")
+		return
+	}
+	fmt.Printf("%sGenerating %d smart playlists...\n\n%s", chalk.Blue, len(c.Playlists), chalk.Reset)
+	albums, err := getAlbums(c)
+	if err != nil {
+		return
+	}
+	plays := make(PlayIndex)
+	for _, s := range c.Playlists {
+		if s.UsesPlays() {
+			path, err := c.PlayIndexFile()
+			if err != nil {
+				return err
+			}
+			if plays, err = LoadPlayIndex(path); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	for _, s := range c.Playlists {
+		p, err := generateSmartPlaylist(c, s, albums, plays)
+		if err != nil {
+			return err
+		}
+		if len(p.contents) == 0 {
+			fmt.Println(chalk.Yellow.Color("- " + s.Name + ": no matching albums"))
+		} else {
+			fmt.Println(chalk.Green.Color("+ " + p.String()))
+		}
+	}
+	fmt.Printf("\n### Generated %d playlists from %d albums.\n", len(c.Playlists), len(albums))
+	return
+}
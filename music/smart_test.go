@@ -0,0 +1,89 @@
+package music
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/barsanuphe/radis/config"
+)
+
+func TestGenerateSmartPlaylists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_smart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sc := config.Config{
+		Paths: config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: dir},
+		Genres: config.Genres{
+			config.Genre{Name: "Jazz", Artists: []string{"Miles"}},
+			config.Genre{Name: "Rock", Artists: []string{"Band"}},
+		},
+		Playlists: config.SmartPlaylists{
+			{Name: "sixties jazz", Genres: []string{"Jazz"}, FromYear: 1955, ToYear: 1965},
+			{Name: "mp3.pls", Format: config.FormatMP3},
+		},
+	}
+	createTestAlbums(t, sc,
+		"Jazz/Miles/Miles (1959) Blue",
+		"Jazz/Miles/Miles (1970) Brew",
+		"Rock/Band/Band (1960) Loud",
+		// not synced yet
+		"INCOMING/Miles (1961) Someday",
+	)
+	// left over from a previous generation
+	if err := ioutil.WriteFile(filepath.Join(dir, "mp3.pls"), []byte{}, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateSmartPlaylists(sc); err != nil {
+		t.Fatal(err)
+	}
+	written, err := ioutil.ReadFile(filepath.Join(dir, "sixties jazz.m3u"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "INCOMING/Miles (1961) Someday/01.flac\nJazz/Miles/Miles (1959) Blue/01.flac\n"
+	if string(written) != expected {
+		t.Errorf("GenerateSmartPlaylists wrote %s, expected %s", string(written), expected)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mp3.pls")); !os.IsNotExist(err) {
+		t.Errorf("GenerateSmartPlaylists should have removed a playlist without matching albums")
+	}
+}
+
+func TestSubGenres(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_smart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sc := config.Config{
+		Paths: config.Paths{Root: dir, UnsortedSubdir: "UNCATEGORIZED", MPDPlaylistDirectory: dir},
+		Genres: config.Genres{
+			config.Genre{Name: "Metal", Artists: []string{"Metallica", "Sleep"}},
+			config.Genre{Name: "Metal/Doom", Artists: []string{"Sleep"}},
+			config.Genre{Name: "Metal-Core", Artists: []string{"Converge"}},
+		},
+	}
+	albums := createTestAlbums(t, sc, "INCOMING/Metallica (1986) Master", "INCOMING/Sleep (1992) Holy Mountain", "INCOMING/Converge (2001) Jane Doe")
+	// the deepest genre is used
+	if expected := filepath.Join(dir, "Metal", "Doom", "Sleep", "Sleep (1992) Holy Mountain"); albums[1].NewPath != expected {
+		t.Errorf("FindNewPath returned %s, expected %s", albums[1].NewPath, expected)
+	}
+
+	metal := config.SmartPlaylist{Name: "metal", Genres: []string{"Metal"}}
+	matching := []string{}
+	for _, a := range albums {
+		if a.matches(metal, nil) {
+			matching = append(matching, a.title)
+		}
+	}
+	if len(matching) != 2 || matching[0] != "Master" || matching[1] != "Holy Mountain" {
+		t.Errorf("smart playlist of a parent genre returned %v", matching)
+	}
+}
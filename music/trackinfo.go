@@ -0,0 +1,301 @@
+package music
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TrackInfo holds what can be read from a music file without external tools.
+type TrackInfo struct {
+	Path     string
+	Duration time.Duration
+	Artist   string
+	Title    string
+	Album    string
+}
+
+// ReadTrackInfo reads the header and tags of a flac or mp3 file.
+// Tracks of CUE sheets are also supported.
+func ReadTrackInfo(path string) (info TrackInfo, err error) {
+	if isVirtualTrack(path) {
+		return readVirtualTrackInfo(path)
+	}
+	info.Path = path
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	audioStart, tags, err := readID3v2(f)
+	if err != nil {
+		return
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		info.Duration, tags, err = readFlacInfo(f)
+	case ".mp3":
+		info.Duration, err = readMP3Duration(f, audioStart)
+	default:
+		err = errors.New("Unsupported file type: " + path)
+	}
+	info.Artist, info.Title, info.Album = tags["ARTIST"], tags["TITLE"], tags["ALBUM"]
+	return
+}
+
+// id3Frames maps ID3v2.2 and ID3v2.3/4 text frames to Vorbis comment names.
+var id3Frames = map[string]string{
+	"TP1": "ARTIST", "TT2": "TITLE", "TAL": "ALBUM",
+	"TPE1": "ARTIST", "TIT2": "TITLE", "TALB": "ALBUM",
+}
+
+// decodeID3Text decodes the contents of an ID3v2 text frame.
+func decodeID3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var text string
+	switch data[0] {
+	case 1:
+		// UTF-16 with a BOM
+		text = decodeText(data[1:])
+	case 2:
+		// UTF-16 big endian without a BOM
+		text = decodeText(append([]byte{0xFE, 0xFF}, data[1:]...))
+	case 3:
+		text = string(data[1:])
+	default:
+		// ISO-8859-1
+		runes := make([]rune, len(data)-1)
+		for i, b := range data[1:] {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	}
+	return strings.TrimRight(text, "\x00")
+}
+
+// readID3v2 reads the text frames of an ID3v2 tag, if there is one,
+// and positions the reader after it.
+func readID3v2(f *os.File) (offset int64, tags map[string]string, err error) {
+	tags = make(map[string]string)
+	header := make([]byte, 10)
+	if _, err = io.ReadFull(f, header); err != nil {
+		return
+	}
+	if string(header[:3]) == "ID3" {
+		// syncsafe integer
+		size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+		offset = 10 + size
+		if header[5]&0x10 != 0 {
+			// footer present
+			offset += 10
+		}
+		tag := make([]byte, size)
+		if _, err = io.ReadFull(f, tag); err != nil {
+			return
+		}
+		version := header[3]
+		frameHeaderSize, idSize := 10, 4
+		if version == 2 {
+			frameHeaderSize, idSize = 6, 3
+		}
+		for i := 0; i+frameHeaderSize < len(tag) && tag[i] != 0; {
+			id := string(tag[i : i+idSize])
+			var frameSize int
+			switch version {
+			case 2:
+				frameSize = int(tag[i+3])<<16 | int(tag[i+4])<<8 | int(tag[i+5])
+			case 3:
+				frameSize = int(binary.BigEndian.Uint32(tag[i+4 : i+8]))
+			default:
+				frameSize = int(tag[i+4])<<21 | int(tag[i+5])<<14 | int(tag[i+6])<<7 | int(tag[i+7])
+			}
+			i += frameHeaderSize
+			if frameSize < 0 || i+frameSize > len(tag) {
+				break
+			}
+			if name, ok := id3Frames[id]; ok {
+				tags[name] = decodeID3Text(tag[i : i+frameSize])
+			}
+			i += frameSize
+		}
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	return
+}
+
+// readFlacInfo reads the STREAMINFO and VORBIS_COMMENT blocks of a flac file.
+func readFlacInfo(f *os.File) (duration time.Duration, tags map[string]string, err error) {
+	tags = make(map[string]string)
+	marker := make([]byte, 4)
+	if _, err = io.ReadFull(f, marker); err != nil {
+		return
+	}
+	if string(marker) != "fLaC" {
+		return 0, tags, errors.New("Not a flac file: " + f.Name())
+	}
+	isLast := false
+	for !isLast {
+		header := make([]byte, 4)
+		if _, err = io.ReadFull(f, header); err != nil {
+			return
+		}
+		isLast = header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		block := make([]byte, int(header[1])<<16|int(header[2])<<8|int(header[3]))
+		if _, err = io.ReadFull(f, block); err != nil {
+			return
+		}
+		switch blockType {
+		case 0:
+			// STREAMINFO
+			if len(block) < 18 {
+				return 0, tags, errors.New("Invalid STREAMINFO in " + f.Name())
+			}
+			sampleRate := int64(block[10])<<12 | int64(block[11])<<4 | int64(block[12])>>4
+			totalSamples := int64(block[13]&0x0F)<<32 | int64(binary.BigEndian.Uint32(block[14:18]))
+			if sampleRate == 0 {
+				return 0, tags, errors.New("Invalid sample rate in " + f.Name())
+			}
+			duration = time.Duration(totalSamples * int64(time.Second) / sampleRate)
+		case 4:
+			// VORBIS_COMMENT, little endian
+			readVorbisComments(block, tags)
+		}
+	}
+	return
+}
+
+// readVorbisComments adds the comments of a VORBIS_COMMENT block to tags, with upper case names.
+func readVorbisComments(block []byte, tags map[string]string) {
+	if len(block) < 4 {
+		return
+	}
+	i := 4 + int(binary.LittleEndian.Uint32(block[0:4]))
+	if i+4 > len(block) {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(block[i : i+4]))
+	i += 4
+	for n := 0; n < count && i+4 <= len(block); n++ {
+		length := int(binary.LittleEndian.Uint32(block[i : i+4]))
+		i += 4
+		if length < 0 || i+length > len(block) {
+			return
+		}
+		comment := string(block[i : i+length])
+		i += length
+		if parts := strings.SplitN(comment, "=", 2); len(parts) == 2 {
+			name := strings.ToUpper(parts[0])
+			if _, ok := tags[name]; !ok {
+				// keep the first value
+				tags[name] = parts[1]
+			}
+		}
+	}
+}
+
+var mp3Bitrates = map[bool][]int64{
+	// MPEG1 Layer III
+	true: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	// MPEG2 & 2.5 Layer III
+	false: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+var mp3SampleRates = []int64{44100, 48000, 32000}
+
+// readMP3Duration finds the first MPEG frame of an mp3 file, and uses either its
+// Xing/VBRI header or its bitrate to find the duration.
+func readMP3Duration(f *os.File, audioStart int64) (duration time.Duration, err error) {
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return
+	}
+	buffer := make([]byte, 64*1024)
+	n, err := io.ReadFull(f, buffer)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	} else if err != nil {
+		return
+	}
+	buffer = buffer[:n]
+
+	for i := 0; i+4 < len(buffer); i++ {
+		if buffer[i] != 0xFF || buffer[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		version := (buffer[i+1] >> 3) & 0x03
+		layer := (buffer[i+1] >> 1) & 0x03
+		bitrateIndex := buffer[i+2] >> 4
+		sampleRateIndex := (buffer[i+2] >> 2) & 0x03
+		if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+			// reserved values or not Layer III, keep looking
+			continue
+		}
+		isMPEG1 := version == 3
+		isMono := buffer[i+3]>>6 == 3
+		sampleRate := mp3SampleRates[sampleRateIndex]
+		samplesPerFrame := int64(1152)
+		switch version {
+		case 0:
+			// MPEG 2.5
+			sampleRate /= 4
+			samplesPerFrame = 576
+		case 2:
+			// MPEG 2
+			sampleRate /= 2
+			samplesPerFrame = 576
+		}
+		// the Xing header comes after the side information
+		xingOffset := 13
+		if isMPEG1 && !isMono {
+			xingOffset = 36
+		} else if isMPEG1 || !isMono {
+			xingOffset = 21
+		}
+		// VBR files have a header with the frame count
+		frames := int64(0)
+		if j := i + xingOffset; j+16 < len(buffer) && (bytes.Equal(buffer[j:j+4], []byte("Xing")) || bytes.Equal(buffer[j:j+4], []byte("Info"))) {
+			if buffer[j+7]&0x01 != 0 {
+				frames = int64(binary.BigEndian.Uint32(buffer[j+8 : j+12]))
+			}
+		} else if j := i + 36; j+18 < len(buffer) && bytes.Equal(buffer[j:j+4], []byte("VBRI")) {
+			frames = int64(binary.BigEndian.Uint32(buffer[j+14 : j+18]))
+		}
+		if frames != 0 {
+			duration = time.Duration(frames * samplesPerFrame * int64(time.Second) / sampleRate)
+		} else {
+			// assume constant bitrate
+			bitrate := mp3Bitrates[isMPEG1][bitrateIndex] * 1000
+			audioSize := fileInfo.Size() - audioStart - int64(i)
+			duration = time.Duration(audioSize * 8 * int64(time.Second) / bitrate)
+		}
+		return
+	}
+	return 0, errors.New("Could not find MPEG frame in " + f.Name())
+}
+
+// fileChecksum returns the SHA1 checksum of a file.
+func fileChecksum(path string) (checksum string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return
+	}
+	checksum = hex.EncodeToString(h.Sum(nil))
+	return
+}
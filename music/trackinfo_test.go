@@ -0,0 +1,65 @@
+package music
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestFlac creates a flac file with a STREAMINFO block and Vorbis comments.
+func writeTestFlac(path string, sampleRate int, samples uint32, comments ...string) error {
+	streamInfo := make([]byte, 34)
+	streamInfo[10] = byte(sampleRate >> 12)
+	streamInfo[11] = byte(sampleRate >> 4)
+	streamInfo[12] = byte(sampleRate<<4) | 0x02
+	binary.BigEndian.PutUint32(streamInfo[14:18], samples)
+	data := append([]byte("fLaC"), 0, 0, 0, 34)
+	data = append(data, streamInfo...)
+
+	block := make([]byte, 8)
+	binary.LittleEndian.PutUint32(block[4:8], uint32(len(comments)))
+	for _, comment := range comments {
+		length := make([]byte, 4)
+		binary.LittleEndian.PutUint32(length, uint32(len(comment)))
+		block = append(append(block, length...), comment...)
+	}
+	data = append(data, 0x84, byte(len(block)>>16), byte(len(block)>>8), byte(len(block)))
+	data = append(data, block...)
+	return ioutil.WriteFile(path, data, 0777)
+}
+
+func TestReadTrackInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radis_trackinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	flac := filepath.Join(dir, "test.flac")
+	if err := writeTestFlac(flac, 44100, 44100*90, "artist=Artist", "TITLE=Title", "ALBUM=Album"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := ReadTrackInfo(flac)
+	if err != nil {
+		t.Errorf("ReadTrackInfo(%s) returned an error: %s", flac, err.Error())
+	}
+	expected := TrackInfo{Path: flac, Duration: 90 * time.Second, Artist: "Artist", Title: "Title", Album: "Album"}
+	if info != expected {
+		t.Errorf("ReadTrackInfo(%s) returned %+v, expected %+v", flac, info, expected)
+	}
+	e := newPlaylistEntry("test.flac", flac, true, nil)
+	if e.Duration != 90 || e.displayTitle() != "Artist - Title" || e.Album != "Album" {
+		t.Errorf("newPlaylistEntry(%s) returned %+v", flac, e)
+	}
+
+	other := filepath.Join(dir, "test.ogg")
+	if err := ioutil.WriteFile(other, []byte("OggS"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTrackInfo(other); err == nil {
+		t.Errorf("ReadTrackInfo(%s) should have returned an error", other)
+	}
+}
@@ -20,16 +20,17 @@ func timeTrack(start time.Time, name string) {
 }
 
 // SortAlbums scans the music collection root and reorders albums according to the configuration files.
-func SortAlbums(c config.Config, doNothing bool) (err error) {
+// It returns the new albums found in IncomingSubdir.
+func SortAlbums(c config.Config, doNothing bool) (newAlbums []Album, err error) {
 	defer timeTrack(time.Now(), "Scanning files")
 
 	movedAlbums := 0
 	uncategorized := 0
 	foundAlbums := 0
-	newAlbums := 0
 	mp3Albums := 0
 
-	dailyPlaylist, monthlyPlaylist := loadCurrentPlaylists(c)
+	rollingPlaylists := loadRollingPlaylists(c)
+	movedPaths := []string{}
 
 	fmt.Printf("%sScanning for albums in %s...\n\n%s", chalk.Blue, c.Paths.Root, chalk.Reset)
 	err = filepath.Walk(c.Paths.Root, func(path string, fileInfo os.FileInfo, walkError error) (err error) {
@@ -63,6 +64,7 @@ func SortAlbums(c config.Config, doNothing bool) (err error) {
 					fmt.Println(chalk.Bold.TextStyle(chalk.Red.Color("!!!\t    " + originalRelative + "\n!!!\t -> " + destRelative)))
 				}
 				if hasMoved {
+					movedPaths = append(movedPaths, path, a.NewPath)
 					fmt.Println(chalk.Yellow.Color("+ " + a.String()))
 					fmt.Println("\t    " + originalRelative + "\n\t -> " + destRelative)
 					movedAlbums++
@@ -70,9 +72,10 @@ func SortAlbums(c config.Config, doNothing bool) (err error) {
 				if a.IsNew(c) {
 					// add to playlist automatically,
 					fmt.Printf("%s\t    Adding to playlist.\n%s", chalk.Green, chalk.Reset)
-					newAlbums++
-					dailyPlaylist.contents = append(dailyPlaylist.contents, a)
-					monthlyPlaylist.contents = append(monthlyPlaylist.contents, a)
+					newAlbums = append(newAlbums, a)
+					for i := range rollingPlaylists {
+						rollingPlaylists[i].AddAlbum(a)
+					}
 				}
 			}
 		}
@@ -82,7 +85,7 @@ func SortAlbums(c config.Config, doNothing bool) (err error) {
 		fmt.Printf("Error!")
 	}
 	fmt.Println(chalk.Blue)
-	fmt.Printf("\n### Found %d albums including %d MP3 albums and %d new albums\n", foundAlbums, mp3Albums, newAlbums)
+	fmt.Printf("\n### Found %d albums including %d MP3 albums and %d new albums\n", foundAlbums, mp3Albums, len(newAlbums))
 	if doNothing {
 		fmt.Printf("### Sync would move %d albums.\n", movedAlbums)
 	} else {
@@ -92,20 +95,34 @@ func SortAlbums(c config.Config, doNothing bool) (err error) {
 		fmt.Println(chalk.Bold.TextStyle(chalk.Red.Color("\n!!!\n!!! " + strconv.Itoa(uncategorized) + " albums are still UNCATEGORIZED !!!\n!!!\n\n")))
 	}
 	if !doNothing {
-		if err := writeCurrentPlaylists(dailyPlaylist, monthlyPlaylist); err != nil {
+		if err := writeRollingPlaylists(c, rollingPlaylists); err != nil {
 			panic(err)
 		}
+		if c.MPD.IsConfigured() {
+			if err := UpdateMPDDatabase(c, movedPaths); err != nil {
+				fmt.Println(chalk.Red.Color("!!! Could not update the MPD database: " + err.Error()))
+			}
+		}
 	}
 	return
 }
 
-// FindNonFlacAlbums scan the music collection root and lists all albums of mp3 files instead of flac.
-func FindNonFlacAlbums(c config.Config) (err error) {
+// FindNonFlacAlbums scan the music collection root and lists all albums of mp3 files instead of flac,
+// and the files that the FilePolicy considers junk, forbidden or does not know.
+// If fix is true, albums incorrectly flagged as [MP3] or not are renamed, and playlists updated.
+// It returns the number of problems that should make fsck fail: unfixed albums and forbidden files.
+func FindNonFlacAlbums(c config.Config, fix bool) (problems int, err error) {
 	defer timeTrack(time.Now(), "Scanning files")
 
 	fmt.Printf("Scanning for non-Flac albums in %s.\n", c.Paths.Root)
 	unFlagged := 0
+	falselyFlagged := 0
 	nonFlacAlbums := 0
+	toFix := []Album{}
+	junkFiles := 0
+	forbiddenFiles := 0
+	unknownFiles := 0
+	splittableAlbums := 0
 	err = filepath.Walk(c.Paths.Root, func(path string, fileInfo os.FileInfo, walkError error) (err error) {
 		// when an album has just been moved, Walk goes through it a second
 		// time with an "file does not exist" error
@@ -116,21 +133,57 @@ func FindNonFlacAlbums(c config.Config) (err error) {
 		if fileInfo.IsDir() {
 			af := Album{Root: c.Paths.Root, Path: path}
 			if af.IsValidAlbum() {
+				// find the genre, which may have its own policy
+				if _, err := af.FindNewPath(c); err != nil {
+					panic(err)
+				}
 				// scan contents for non-flac
-				isNonFlac, err := af.HasNonFlacFiles()
+				report, err := af.CheckFiles(c.FilePolicy)
 				if err != nil {
 					panic(err)
 				}
 				relativePath, _ := filepath.Rel(c.Paths.Root, path)
+				isNonFlac := len(report[config.AudioLossy]) != 0
 				if isNonFlac {
 					fmt.Println("- ", relativePath)
 					nonFlacAlbums++
 				}
 				if isNonFlac && !af.IsMP3 {
 					unFlagged++
+					toFix = append(toFix, af)
 					fmt.Println("!!! ", relativePath, " not flagged as non FLAC!!!")
 				}
-				// NOTE: find falsely tagged folders? is that a thing?
+				if !isNonFlac && af.IsMP3 && len(report[config.AudioLossless]) != 0 {
+					falselyFlagged++
+					toFix = append(toFix, af)
+					fmt.Println("!!! ", relativePath, " flagged as non FLAC but only contains FLAC!!!")
+				}
+				for _, file := range report[config.Junk] {
+					fmt.Println(chalk.Yellow.Color("Found junk file " + file + " in " + relativePath))
+					junkFiles++
+				}
+				for _, file := range report[config.Unknown] {
+					fmt.Println("Found suspicious file " + file + " in " + relativePath)
+					unknownFiles++
+				}
+				for _, file := range report[config.Forbidden] {
+					fmt.Println(chalk.Bold.TextStyle(chalk.Red.Color("!!! Found forbidden file " + file + " in " + relativePath)))
+					forbiddenFiles++
+				}
+				// check CUE sheets
+				sheets, err := af.GetCueSheets()
+				if err != nil {
+					panic(err)
+				}
+				for _, cs := range sheets {
+					for _, missing := range cs.MissingFiles() {
+						fmt.Println(chalk.Yellow.Color("CUE sheet " + filepath.Base(cs.Path) + " in " + relativePath + " references missing file " + missing))
+					}
+					if cs.IsImage() {
+						fmt.Printf("Found image+cue album that could be split (%d tracks): %s\n", len(cs.Tracks), relativePath)
+						splittableAlbums++
+					}
+				}
 			}
 		}
 		return
@@ -139,9 +192,39 @@ func FindNonFlacAlbums(c config.Config) (err error) {
 		fmt.Printf("Error!")
 	}
 	fmt.Printf("\n### Found %d non-Flac albums, including %d incorrectly flagged.\n", nonFlacAlbums, unFlagged)
+	fmt.Printf("### Found %d FLAC albums incorrectly flagged as non-Flac.\n", falselyFlagged)
+	fmt.Printf("### Found %d junk, %d suspicious and %d forbidden files.\n", junkFiles, unknownFiles, forbiddenFiles)
+	fmt.Printf("### Found %d image+cue albums that could be split.\n", splittableAlbums)
 	if unFlagged != 0 {
 		fmt.Printf("\n!!!\n!!! %d album(s) remain UNCATEGORIZED !!!\n!!!\n\n", unFlagged)
 	}
+	problems = unFlagged + falselyFlagged + forbiddenFiles
+
+	if fix && len(toFix) != 0 {
+		// rename after the walk, so that it does not go through renamed albums
+		fmt.Printf("\n%sFixing %d albums...\n\n%s", chalk.Blue, len(toFix), chalk.Reset)
+		renamed := make(map[string]string)
+		for _, a := range toFix {
+			originalPath := a.Path
+			originalRelative, _ := filepath.Rel(a.Root, a.Path)
+			hasMoved, err := a.SetMP3Flag(!a.IsMP3, false)
+			destRelative, _ := filepath.Rel(a.Root, a.NewPath)
+			if err != nil {
+				fmt.Println(chalk.Bold.TextStyle(chalk.Red.Color("!!! ERROR MOVING " + a.String())))
+				fmt.Println(chalk.Bold.TextStyle(chalk.Red.Color("!!!\t    " + originalRelative + "\n!!!\t -> " + destRelative)))
+				continue
+			}
+			if hasMoved {
+				fmt.Println(chalk.Yellow.Color("+ " + a.String()))
+				fmt.Println("\t    " + originalRelative + "\n\t -> " + destRelative)
+				renamed[originalPath] = a.NewPath
+				problems--
+			}
+		}
+		if err = renameInPlaylists(c, renamed); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -187,3 +270,95 @@ func DeleteEmptyFolders(c config.Config) (err error) {
 	fmt.Printf("\n### Removed %d albums.\n", deletedDirectories)
 	return
 }
+
+// getAlbums scans the music collection root and returns all albums, with their aliases resolved.
+func getAlbums(c config.Config) (albums []Album, err error) {
+	err = filepath.Walk(c.Paths.Root, func(path string, fileInfo os.FileInfo, walkError error) (err error) {
+		if os.IsNotExist(walkError) {
+			return
+		}
+		if fileInfo.IsDir() {
+			a := Album{Root: c.Paths.Root, Path: path}
+			if a.IsValidAlbum() {
+				if _, err = a.FindNewPath(c); err != nil {
+					return
+				}
+				albums = append(albums, a)
+			}
+		}
+		return
+	})
+	return
+}
+
+// FindDuplicateAlbums scans the music collection root and lists albums that exist more than once.
+func FindDuplicateAlbums(c config.Config) (err error) {
+	defer timeTrack(time.Now(), "Scanning files")
+
+	fmt.Printf("Scanning for duplicate albums in %s.\n\n", c.Paths.Root)
+	albums, err := getAlbums(c)
+	if err != nil {
+		return
+	}
+	duplicates, err := FindDuplicates(albums)
+	if err != nil {
+		return
+	}
+	for _, d := range duplicates {
+		fmt.Println(chalk.Yellow.Color(d.String()))
+	}
+	fmt.Printf("\n### Found %d groups of duplicate albums among %d albums.\n", len(duplicates), len(albums))
+	return
+}
+
+// CheckRipLogs scans the music collection root and lists FLAC albums without rip logs,
+// or with logs showing errors or failed AccurateRip checks.
+func CheckRipLogs(c config.Config) (err error) {
+	defer timeTrack(time.Now(), "Scanning files")
+
+	fmt.Printf("Scanning for rip logs in %s.\n\n", c.Paths.Root)
+	albums, err := getAlbums(c)
+	if err != nil {
+		return
+	}
+	flacAlbums := 0
+	missingLogs := 0
+	withErrors := 0
+	failedAccurateRip := 0
+	for _, a := range albums {
+		if a.IsMP3 {
+			continue
+		}
+		flacAlbums++
+		logs, err := a.GetRipLogs()
+		if err != nil {
+			return err
+		}
+		relativePath, _ := filepath.Rel(c.Paths.Root, a.Path)
+		if len(logs) == 0 {
+			fmt.Println("- No log: " + relativePath)
+			missingLogs++
+			continue
+		}
+		hasErrors, hasFailed := false, false
+		for _, r := range logs {
+			hasErrors = hasErrors || len(r.Errors) != 0 || r.CRCMismatches != 0
+			hasFailed = hasFailed || r.HasFailedAccurateRip()
+		}
+		if hasErrors {
+			withErrors++
+		}
+		if hasFailed {
+			failedAccurateRip++
+		}
+		if hasErrors || hasFailed {
+			fmt.Println(chalk.Yellow.Color(fmt.Sprintf("! %s (score %d)", relativePath, RipScore(logs))))
+			for _, r := range logs {
+				fmt.Print(r.String())
+			}
+		}
+	}
+	fmt.Printf("\n### Found %d FLAC albums: %d without logs, %d with errors, %d with failed AccurateRip checks.\n",
+		flacAlbums, missingLogs, withErrors, failedAccurateRip)
+	return
+}
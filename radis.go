@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/barsanuphe/radis/config"
 	"github.com/barsanuphe/radis/directory"
@@ -13,15 +15,16 @@ import (
 )
 
 func main() {
-	fmt.Println(chalk.Bold.TextStyle("\n# # # R A D I S # # #\n"))
+	// on stderr, so that JSON output can be parsed
+	fmt.Fprintln(os.Stderr, chalk.Bold.TextStyle("\n# # # R A D I S # # #\n"))
 
 	// load config
 	rc := config.Config{}
 	if err := rc.Load(); err != nil {
 		panic(err)
 	}
-	// check config
-	if err := rc.Check(); err != nil {
+	// check config, unless checking it reports the problems
+	if err := rc.Check(); err != nil && !isConfigCheck(os.Args) {
 		panic(err)
 	}
 
@@ -48,15 +51,106 @@ func main() {
 				{
 					Name:    "save",
 					Aliases: []string{"sa"},
-					Usage:   "reorder and save configuration files",
+					Usage:   "reorder and save configuration files, keeping their comments",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "keep-order",
+							Usage: "keep the order of genres and main aliases, only sort artists and aliases",
+						},
+					},
 					Action: func(c *cli.Context) {
-
-						if err := rc.Write(); err != nil {
+						if err := rc.Write(c.Bool("keep-order")); err != nil {
 							panic(err)
 						}
 						fmt.Println("Configuration files saved.")
 					},
 				},
+				{
+					Name:  "genre",
+					Usage: "edit genres and show the albums that would move",
+					Subcommands: []cli.Command{
+						{
+							Name:  "add",
+							Usage: "add an artist to a genre",
+							Action: func(c *cli.Context) {
+								editConfig(c, rc, "genre add <genre> <artist>", func(edited *config.Config, args []string) error { return edited.Genres.AddArtist(args[0], args[1]) })
+							},
+						},
+						{
+							Name:  "rm",
+							Usage: "remove an artist from a genre",
+							Action: func(c *cli.Context) {
+								editConfig(c, rc, "genre rm <genre> <artist>", func(edited *config.Config, args []string) error { return edited.Genres.RemoveArtist(args[0], args[1]) })
+							},
+						},
+						{
+							Name:  "mv",
+							Usage: "move an artist to another genre",
+							Action: func(c *cli.Context) {
+								editConfig(c, rc, "genre mv <genre> <artist>", func(edited *config.Config, args []string) error { return edited.Genres.MoveArtist(args[0], args[1]) })
+							},
+						},
+						{
+							Name:  "rename",
+							Usage: "rename a genre and its sub-genres",
+							Action: func(c *cli.Context) {
+								editConfig(c, rc, "genre rename <genre> <new name>", func(edited *config.Config, args []string) error { return edited.Genres.Rename(args[0], args[1]) })
+							},
+						},
+					},
+				},
+				{
+					Name:  "alias",
+					Usage: "edit aliases and show the albums that would move",
+					Subcommands: []cli.Command{
+						{
+							Name:  "add",
+							Usage: "add an alias to an artist",
+							Action: func(c *cli.Context) {
+								editConfig(c, rc, "alias add <main alias> <alias>", func(edited *config.Config, args []string) error { return edited.Aliases.AddAlias(args[0], args[1]) })
+							},
+						},
+						{
+							Name:  "rm",
+							Usage: "remove an alias of an artist",
+							Action: func(c *cli.Context) {
+								editConfig(c, rc, "alias rm <main alias> <alias>", func(edited *config.Config, args []string) error { return edited.Aliases.RemoveAlias(args[0], args[1]) })
+							},
+						},
+					},
+				},
+				{
+					Name:  "check",
+					Usage: "lint genres and aliases, and compare them with the collection",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "json",
+							Usage: "print the findings as JSON",
+						},
+					},
+					Action: func(c *cli.Context) {
+						findings := rc.Lint()
+						if c.Bool("json") {
+							data, err := json.MarshalIndent(findings, "", "  ")
+							if err != nil {
+								fmt.Println(err.Error())
+								os.Exit(2)
+							}
+							fmt.Println(string(data))
+						} else {
+							colors := map[string]chalk.Color{config.SeverityError: chalk.Red, config.SeverityWarning: chalk.Yellow, config.SeverityInfo: chalk.Blue}
+							for _, f := range findings {
+								fmt.Println(colors[f.Severity].Color(f.String()))
+							}
+							fmt.Printf("\n### Found %d problems in the configuration.\n", len(findings))
+						}
+						for _, f := range findings {
+							if f.Severity == config.SeverityError {
+								os.Exit(1)
+							}
+						}
+					},
+				},
 			},
 		},
 		{
@@ -83,10 +177,118 @@ func main() {
 					Name:    "update",
 					Aliases: []string{"up"},
 					Usage:   "update playlist according to configuration.",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "extended",
+							Usage: "write #EXTM3U and #EXTINF directives from the tags of the files",
+						},
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "update all playlists in MPDPlaylistDirectory",
+						},
+					},
 					Action: func(c *cli.Context) {
+						if c.Bool("all") {
+							if err := music.UpdateAllPlaylists(rc); err != nil {
+								fmt.Println(err.Error())
+							}
+							return
+						}
 						fmt.Println("Updating " + c.Args().First())
-						p := music.Playlist{Filename: filepath.Join(rc.Paths.MPDPlaylistDirectory, c.Args().First())}
-						if err := p.UpdateAndSave(rc); err != nil {
+						p := music.Playlist{Filename: filepath.Join(rc.Paths.MPDPlaylistDirectory, c.Args().First()), Extended: c.Bool("extended")}
+						report, err := p.UpdateAndSave(rc)
+						if err != nil {
+							fmt.Println(err.Error())
+							return
+						}
+						fmt.Println(report.String())
+					},
+				},
+				{
+					Name:  "check",
+					Usage: "list missing files, bad paths and duplicates in a playlist, without modifying it.",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "check all playlists in MPDPlaylistDirectory",
+						},
+						cli.BoolFlag{
+							Name:  "json",
+							Usage: "print the results as JSON",
+						},
+					},
+					Action: func(c *cli.Context) {
+						if !c.Bool("all") && c.Args().First() == "" {
+							fmt.Println("Usage: radis playlist check <playlist>|--all")
+							os.Exit(2)
+						}
+						problems, err := music.CheckPlaylists(rc, c.Args().First(), c.Bool("json"))
+						if err != nil {
+							fmt.Println(err.Error())
+							os.Exit(2)
+						}
+						if problems != 0 {
+							os.Exit(1)
+						}
+					},
+				},
+				{
+					Name:  "export",
+					Usage: "write copies of a playlist, or all playlists, for another device.",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "profile",
+							Usage: "export profile defined in radis.yaml",
+						},
+					},
+					Action: func(c *cli.Context) {
+						if err := music.ExportPlaylists(rc, c.String("profile"), c.Args().First()); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+				setOperationCommand(rc, music.OperationMerge, "write the tracks of several playlists to a new one."),
+				setOperationCommand(rc, music.OperationIntersect, "write the tracks found in two playlists to a new one."),
+				setOperationCommand(rc, music.OperationSubtract, "write the tracks of a playlist that are not in another one to a new one."),
+				setOperationCommand(rc, music.OperationDiff, "show the differences between two playlists."),
+				{
+					Name:  "sort",
+					Usage: "sort or shuffle the albums of a playlist, keeping their tracks together.",
+					Flags: orderFlags("year"),
+					Action: func(c *cli.Context) {
+						if err := music.OrderPlaylist(rc, c.Args().First(), c.String("by"), c.Bool("reverse"), int64(c.Int("seed"))); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+				{
+					Name:  "repair",
+					Usage: "find the entries of a playlist, or of all playlists, that cannot be found anymore, by album or by checksum.",
+					Action: func(c *cli.Context) {
+						if err := music.RepairPlaylists(rc, c.Args().First()); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+				{
+					Name:    "generate",
+					Aliases: []string{"gen"},
+					Usage:   "generate the smart playlists defined in radis_playlists.yaml.",
+					Action: func(c *cli.Context) {
+						if err := music.GenerateSmartPlaylists(rc); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+				{
+					Name:  "convert",
+					Usage: "convert a playlist to another format: m3u, xspf or pls.",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) != 2 {
+							fmt.Println("Usage: radis playlist convert <input> <output>")
+							return
+						}
+						if err := music.ConvertPlaylist(rc, c.Args().Get(0), c.Args().Get(1)); err != nil {
 							fmt.Println(err.Error())
 						}
 					},
@@ -102,15 +304,47 @@ func main() {
 					Name:    "sync",
 					Aliases: []string{"s"},
 					Usage:   "sync folder according to configuration",
+					Flags: append(orderFlags(""),
+						cli.BoolFlag{
+							Name:  "update-playlists",
+							Usage: "update all playlists in MPDPlaylistDirectory after the sync",
+						},
+						cli.BoolFlag{
+							Name:  "enqueue",
+							Usage: "append the new albums to the MPD queue",
+						},
+					),
 					Action: func(c *cli.Context) {
 						// sort albums
-						if err := music.SortAlbums(rc, false); err != nil {
+						newAlbums, err := music.SortAlbums(rc, false)
+						if err != nil {
 							panic(err)
 						}
 						// scan again to remove empty directories
 						if err := music.DeleteEmptyFolders(rc); err != nil {
 							panic(err)
 						}
+						// point playlists to the new paths
+						if c.Bool("update-playlists") {
+							if err := music.UpdateAllPlaylists(rc); err != nil {
+								fmt.Println(err.Error())
+							}
+						}
+						// regenerate smart playlists with the new paths
+						if err := music.GenerateSmartPlaylists(rc); err != nil {
+							fmt.Println(err.Error())
+						}
+						// make MPD read the playlists again
+						if rc.MPD.IsConfigured() {
+							if err := music.CheckMPDPlaylists(rc); err != nil {
+								fmt.Println(err.Error())
+							}
+						}
+						if c.Bool("enqueue") {
+							if err := music.EnqueueAlbums(rc, newAlbums, c.String("by"), c.Bool("reverse"), orderSeed(c)); err != nil {
+								fmt.Println(err.Error())
+							}
+						}
 					},
 				},
 				{
@@ -119,7 +353,7 @@ func main() {
 					Usage:   "check against configuration",
 					Action: func(c *cli.Context) {
 						// sort albums
-						if err := music.SortAlbums(rc, true); err != nil {
+						if _, err := music.SortAlbums(rc, true); err != nil {
 							panic(err)
 						}
 					},
@@ -128,16 +362,218 @@ func main() {
 					Name:    "fsck",
 					Aliases: []string{"findMP3"},
 					Usage:   "check every album is a flac version, list the heretics.",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "fix",
+							Usage: "add or remove the [MP3] suffix of incorrectly flagged albums, and update playlists",
+						},
+					},
 					Action: func(c *cli.Context) {
 						// list non Flac albums
-						if err := music.FindNonFlacAlbums(rc); err != nil {
+						problems, err := music.FindNonFlacAlbums(rc, c.Bool("fix"))
+						if err != nil {
+							panic(err)
+						}
+						if problems != 0 {
+							os.Exit(1)
+						}
+					},
+				},
+				{
+					Name:    "dupes",
+					Aliases: []string{"d"},
+					Usage:   "find albums that exist more than once, and suggest which to keep.",
+					Action: func(c *cli.Context) {
+						if err := music.FindDuplicateAlbums(rc); err != nil {
 							panic(err)
 						}
 					},
 				},
+				{
+					Name:    "riplogs",
+					Aliases: []string{"r"},
+					Usage:   "list albums without rip logs, or with errors or failed AccurateRip checks.",
+					Action: func(c *cli.Context) {
+						if err := music.CheckRipLogs(rc); err != nil {
+							panic(err)
+						}
+					},
+				},
+				{
+					Name:  "stats",
+					Usage: "show what is played most and least.",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "plays",
+							Usage: "show most played, recently played and never played albums",
+						},
+						cli.IntFlag{
+							Name:  "limit",
+							Value: 10,
+							Usage: "number of albums to list",
+						},
+						cli.StringFlag{
+							Name:  "recent",
+							Value: "30d",
+							Usage: "how recently albums must have been played to be listed as recently played: 30d, 2w, 12h...",
+						},
+					},
+					Action: func(c *cli.Context) {
+						if !c.Bool("plays") {
+							fmt.Println("Usage: radis collection stats --plays [--limit 10] [--recent 30d]")
+							os.Exit(2)
+						}
+						recent, err := config.ParseAge(c.String("recent"))
+						if err != nil {
+							fmt.Println(err.Error())
+							return
+						}
+						if err := music.ShowPlayStats(rc, c.Int("limit"), recent); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+				{
+					Name:    "mirror",
+					Aliases: []string{"m"},
+					Usage:   "mirror the selected genres to a directory, transcoding flac files.",
+					Action: func(c *cli.Context) {
+						if c.Args().First() == "" {
+							fmt.Println("Destination directory required.")
+							return
+						}
+						if err := music.MirrorCollection(rc, c.Args().First()); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+			},
+		},
+		{
+			Name:  "play",
+			Usage: "options for playing with MPD",
+			Subcommands: []cli.Command{
+				{
+					Name:  "new",
+					Usage: "append the albums of the current rolling playlist to the MPD queue.",
+					Flags: orderFlags(""),
+					Action: func(c *cli.Context) {
+						if err := music.EnqueueNewAlbums(rc, c.String("by"), c.Bool("reverse"), orderSeed(c)); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+			},
+		},
+		{
+			Name:  "plays",
+			Usage: "options for play statistics",
+			Subcommands: []cli.Command{
+				{
+					Name:  "update",
+					Usage: "read play counts and ratings from MPD stickers, and played songs from mpd.log.",
+					Action: func(c *cli.Context) {
+						if err := music.UpdatePlays(rc); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
+				{
+					Name:  "import",
+					Usage: "add the plays of a .scrobbler.log written by Rockbox.",
+					Action: func(c *cli.Context) {
+						if c.Args().First() == "" {
+							fmt.Println("Scrobbler log required.")
+							return
+						}
+						if err := music.ImportScrobblerLog(rc, c.Args().First()); err != nil {
+							fmt.Println(err.Error())
+						}
+					},
+				},
 			},
 		},
 	}
 
 	app.Run(os.Args)
 }
+
+// isConfigCheck indicates if radis was called to check its configuration.
+func isConfigCheck(args []string) bool {
+	return len(args) > 2 && (args[1] == "config" || args[1] == "c") && args[2] == "check"
+}
+
+// editConfig changes the genres or aliases, shows the albums a sync would then move, and saves the configuration.
+func editConfig(c *cli.Context, rc config.Config, usage string, edit func(edited *config.Config, args []string) error) {
+	if len(c.Args()) != 2 {
+		fmt.Println("Usage: radis config " + usage)
+		os.Exit(2)
+	}
+	if err := edit(&rc, c.Args()); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if _, err := music.SortAlbums(rc, true); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	// the files are edited, not reorganized
+	if err := rc.Write(true); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("Configuration files saved, run radis collection sync to move the albums.")
+}
+
+// orderFlags are the options to sort or shuffle albums.
+func orderFlags(defaultOrder string) []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "by",
+			Value: defaultOrder,
+			Usage: "year, artist, genre, added or shuffle",
+		},
+		cli.BoolFlag{
+			Name:  "reverse",
+			Usage: "reverse the order",
+		},
+		cli.IntFlag{
+			Name:  "seed",
+			Usage: "seed for a reproducible shuffle",
+		},
+	}
+}
+
+// orderSeed returns the seed for shuffling albums, a random one if none was given.
+func orderSeed(c *cli.Context) int64 {
+	if seed := c.Int("seed"); seed != 0 {
+		return int64(seed)
+	}
+	return time.Now().UnixNano()
+}
+
+// setOperationCommand creates the command for a set operation on playlists.
+func setOperationCommand(rc config.Config, operation, usage string) cli.Command {
+	flags := []cli.Flag{
+		cli.BoolFlag{
+			Name:  "albums",
+			Usage: "compare whole albums instead of tracks",
+		},
+	}
+	if operation != music.OperationDiff {
+		flags = append(flags, cli.StringFlag{
+			Name:  "output, o",
+			Usage: "playlist to write",
+		})
+	}
+	return cli.Command{
+		Name:  operation,
+		Usage: usage,
+		Flags: flags,
+		Action: func(c *cli.Context) {
+			if err := music.CombinePlaylists(rc, operation, c.Args(), c.String("output"), c.Bool("albums")); err != nil {
+				fmt.Println(err.Error())
+			}
+		},
+	}
+}